@@ -5,10 +5,16 @@ import (
 	"log"
 	"net/rpc"
 	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/watzon/go-up/internal/daemon"
+	"github.com/watzon/go-up/internal/notify"
 	"github.com/watzon/go-up/internal/tui"
 	"github.com/watzon/go-up/internal/types"
 )
@@ -28,6 +34,10 @@ func initConfig() {
 	// Set defaults
 	viper.SetDefault("daemon.host", "localhost")
 	viper.SetDefault("daemon.port", 1234)
+	viper.SetDefault("daemon.workers", 16)
+	viper.SetDefault("daemon.push_port", 1235)
+	viper.SetDefault("daemon.push_url", "")
+	viper.SetDefault("daemon.allow_exec", false)
 
 	// Read config
 	if err := viper.ReadInConfig(); err != nil {
@@ -46,6 +56,46 @@ func main() {
 	var debugMode bool
 	var daemonHost string
 	var daemonPort int
+	var monitorType string
+	var monitorInterval, monitorTimeout time.Duration
+	var monitorRetries int
+	var monitorRetryInterval time.Duration
+	var historyCount int
+	var historyRetries bool
+	var incidentMonitor string
+	var incidentCount int
+	var incidentOpen bool
+	var tcpSend, tcpExpect string
+	var dnsRecordType, dnsResolver string
+	var dnsExpected []string
+	var grpcService string
+	var grpcTLS bool
+	var bodyContains, bodyNotContains, bodyRegex, jsonAssertions []string
+	var maxBodySize int64
+	var softAssertions bool
+	var degradedThreshold time.Duration
+	var httpMethod, httpBody string
+	var httpHeaders, acceptedStatus []string
+	var noFollowRedirects bool
+	var tlsSkipVerify bool
+	var tlsCABundle string
+	var certWarnDays, certDownDays int
+	var pushGrace time.Duration
+	var alertChannels, alertEvents []string
+	var alertSeverity string
+	var alertRoutingKeys map[string]string
+	var alertDelay, alertRepeat, escalateAfter time.Duration
+	var escalateChannels []string
+	var alertMonitor string
+	var alertCount int
+	var ackBy string
+	var notificationMonitor, notificationChannel string
+	var notificationCount int
+	var monitorTags []string
+	var maintenanceMonitors, maintenanceTags []string
+	var maintenanceMode, maintenanceStart, maintenanceEnd string
+	var maintenanceCron, maintenanceWeekly, maintenanceTimezone string
+	var maintenanceDuration time.Duration
 
 	// Initialize config before creating commands
 	initConfig()
@@ -73,14 +123,58 @@ func main() {
 		Short: "Starts the go-up daemon",
 		Run: func(cmd *cobra.Command, args []string) {
 			log.Printf("Starting daemon on %s:%d...", daemonHost, daemonPort)
-			daemon.Start(daemonHost, daemonPort)
+			var channels map[string]notify.ChannelConfig
+			if err := viper.UnmarshalKey("notifications.channels", &channels); err != nil {
+				log.Fatalf("Error reading notification channels: %v", err)
+			}
+			daemon.Start(daemon.Config{
+				Host:      daemonHost,
+				Port:      daemonPort,
+				Workers:   viper.GetInt("daemon.workers"),
+				PushPort:  viper.GetInt("daemon.push_port"),
+				PushURL:   viper.GetString("daemon.push_url"),
+				AllowExec: viper.GetBool("daemon.allow_exec"),
+				Channels:  channels,
+			})
+		},
+	}
+
+	var daemonStatsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: "Show check scheduler statistics",
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var stats types.SchedulerStats
+			err = client.Call("Service.GetSchedulerStats", struct{}{}, &stats)
+			if err != nil {
+				log.Fatalf("Error getting scheduler stats: %v", err)
+			}
+
+			fmt.Printf("Workers: %d\n", stats.Workers)
+			fmt.Printf("Active Monitors: %d\n", stats.Monitors)
+			fmt.Printf("Queue Depth: %d\n", stats.QueueDepth)
+			fmt.Printf("Running Checks: %d\n", stats.Running)
+			fmt.Printf("Completed Checks: %d\n", stats.Completed)
+			fmt.Printf("Skipped Checks: %d\n", stats.Skipped)
+			fmt.Printf("Lag (last/avg/max): %s / %s / %s\n",
+				stats.LastLag.Round(time.Millisecond), stats.AvgLag.Round(time.Millisecond), stats.MaxLag.Round(time.Millisecond))
 		},
 	}
+	startDaemonCmd.AddCommand(daemonStatsCmd)
 
 	var addMonitorCmd = &cobra.Command{
-		Use:   "add [name] [url]",
+		Use:   "add [name] [url|host:port|hostname|command]",
 		Short: "Add a new monitor",
 		Run: func(cmd *cobra.Command, args []string) {
+			// Push monitors are given a URL by the daemon
+			if monitorType == types.MonitorTypePush && len(args) == 1 {
+				args = append(args, "")
+			}
 			if len(args) < 2 {
 				fmt.Println("Please provide a name and a URL to monitor")
 				return
@@ -91,8 +185,86 @@ func main() {
 			}
 			defer client.Close()
 
+			var assertions []types.Assertion
+			for _, v := range bodyContains {
+				assertions = append(assertions, types.Assertion{Type: types.AssertContains, Value: v})
+			}
+			for _, v := range bodyNotContains {
+				assertions = append(assertions, types.Assertion{Type: types.AssertNotContains, Value: v})
+			}
+			for _, v := range bodyRegex {
+				assertions = append(assertions, types.Assertion{Type: types.AssertRegex, Value: v})
+			}
+			for _, v := range jsonAssertions {
+				assertion, err := parseJSONAssertion(v)
+				if err != nil {
+					log.Fatalf("Error parsing JSON assertion: %v", err)
+				}
+				assertions = append(assertions, assertion)
+			}
+			for i := range assertions {
+				assertions[i].Soft = softAssertions
+			}
+
+			headers := make(map[string]string)
+			for _, h := range httpHeaders {
+				key, value, ok := strings.Cut(h, ":")
+				if !ok {
+					log.Fatalf("Invalid header %q, expected \"Name: value\"", h)
+				}
+				headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+
+			// The daemon is sent the bundle, it doesn't read files itself
+			var caBundle string
+			if tlsCABundle != "" {
+				pem, err := os.ReadFile(tlsCABundle)
+				if err != nil {
+					log.Fatalf("Error reading CA bundle: %v", err)
+				}
+				caBundle = string(pem)
+			}
+
 			var reply string
-			err = client.Call("Service.AddMonitor", struct{ Name, URL string }{args[0], args[1]}, &reply)
+			monitor := types.Monitor{
+				Name:              args[0],
+				URL:               args[1],
+				Type:              monitorType,
+				Interval:          monitorInterval,
+				Timeout:           monitorTimeout,
+				Retries:           monitorRetries,
+				RetryInterval:     monitorRetryInterval,
+				Method:            httpMethod,
+				Headers:           headers,
+				Body:              httpBody,
+				AcceptedStatus:    acceptedStatus,
+				NoFollowRedirects: noFollowRedirects,
+				TLSSkipVerify:     tlsSkipVerify,
+				TLSCABundle:       caBundle,
+				CertWarnDays:      certWarnDays,
+				CertDownDays:      certDownDays,
+				TCPSend:           tcpSend,
+				TCPExpect:         tcpExpect,
+				DNSRecordType:     dnsRecordType,
+				DNSResolver:       dnsResolver,
+				DNSExpected:       dnsExpected,
+				GRPCService:       grpcService,
+				GRPCTLS:           grpcTLS,
+				Assertions:        assertions,
+				MaxBodySize:       maxBodySize,
+				DegradedThreshold: degradedThreshold,
+				PushGrace:         pushGrace,
+				AlertChannels:     alertChannels,
+				AlertEvents:       alertEvents,
+				AlertSeverity:     alertSeverity,
+				AlertRoutingKeys:  alertRoutingKeys,
+				AlertDelay:        alertDelay,
+				AlertRepeat:       alertRepeat,
+				EscalateAfter:     escalateAfter,
+				EscalateChannels:  escalateChannels,
+				Tags:              monitorTags,
+			}
+			err = client.Call("Service.AddMonitor", monitor, &reply)
 			if err != nil {
 				log.Fatalf("Error adding monitor: %v", err)
 			}
@@ -100,6 +272,114 @@ func main() {
 		},
 	}
 
+	addMonitorCmd.Flags().StringVar(&monitorType, "type", types.MonitorTypeHTTP, "Monitor type (http, tcp, dns, grpc, exec, push)")
+	addMonitorCmd.Flags().DurationVar(&monitorInterval, "interval", 0, "Time between checks, or expected time between heartbeats for push monitors (default 60s)")
+	addMonitorCmd.Flags().DurationVar(&monitorTimeout, "timeout", 0, "Check timeout (default 10s)")
+	addMonitorCmd.Flags().IntVar(&monitorRetries, "retries", 0, "Failed attempts to retry before the monitor is recorded as down")
+	addMonitorCmd.Flags().DurationVar(&monitorRetryInterval, "retry-interval", 0, "Time between retry attempts (default 10s)")
+	addMonitorCmd.Flags().StringVar(&httpMethod, "method", "GET", "HTTP method, e.g. HEAD, POST or PUT (http only)")
+	addMonitorCmd.Flags().StringArrayVar(&httpHeaders, "header", nil, "Request header as \"Name: value\" (http only, repeatable)")
+	addMonitorCmd.Flags().StringVar(&httpBody, "body", "", "Request body (http only)")
+	addMonitorCmd.Flags().StringSliceVar(&acceptedStatus, "accept-status", nil, "Accepted status codes, ranges or classes, e.g. 200-299,401,3xx (http only, default 200-299)")
+	addMonitorCmd.Flags().BoolVar(&noFollowRedirects, "no-follow-redirects", false, "Don't follow redirects, so 3xx responses can be accepted (http only)")
+	addMonitorCmd.Flags().BoolVar(&tlsSkipVerify, "insecure", false, "Don't fail the check when the certificate can't be verified (http and grpc)")
+	addMonitorCmd.Flags().StringVar(&tlsCABundle, "ca-bundle", "", "PEM file with CA certificates to verify against instead of the system roots (http and grpc)")
+	addMonitorCmd.Flags().IntVar(&certWarnDays, "cert-warn-days", 14, "Warn when the certificate chain expires within this many days, 0 to disable (http and grpc)")
+	addMonitorCmd.Flags().IntVar(&certDownDays, "cert-down-days", 0, "Mark the monitor down when the certificate chain expires within this many days, 0 to disable (http and grpc)")
+	addMonitorCmd.Flags().StringVar(&tcpSend, "send", "", "Data to send after connecting (tcp only, supports \\r\\n escapes)")
+	addMonitorCmd.Flags().StringVar(&tcpExpect, "expect", "", "String the response must contain (tcp only)")
+	addMonitorCmd.Flags().StringVar(&dnsRecordType, "record-type", "A", "Record type to query: A, AAAA, CNAME, MX or TXT (dns only)")
+	addMonitorCmd.Flags().StringVar(&dnsResolver, "resolver", "", "Resolver address as host[:port] (dns only, default system resolver)")
+	addMonitorCmd.Flags().StringSliceVar(&dnsExpected, "dns-expect", nil, "Expected answers, e.g. 1.2.3.4 or \"10 mail.example.com\" for MX (dns only)")
+	addMonitorCmd.Flags().StringVar(&grpcService, "grpc-service", "", "Service name to check, empty for the whole server (grpc only)")
+	addMonitorCmd.Flags().BoolVar(&grpcTLS, "grpc-tls", false, "Connect with TLS instead of plaintext (grpc only)")
+	addMonitorCmd.Flags().StringArrayVar(&bodyContains, "contains", nil, "String the response body must contain (http only, repeatable)")
+	addMonitorCmd.Flags().StringArrayVar(&bodyNotContains, "not-contains", nil, "String the response body must not contain (http only, repeatable)")
+	addMonitorCmd.Flags().StringArrayVar(&bodyRegex, "regex", nil, "Regular expression the response body must match (http only, repeatable)")
+	addMonitorCmd.Flags().StringArrayVar(&jsonAssertions, "json", nil, "JSON assertion as \"<path> <op> [value]\", e.g. \"$.db == up\" or \"$.items.length() > 0\" (http only, repeatable)")
+	addMonitorCmd.Flags().DurationVar(&pushGrace, "grace", 0, "Extra time a heartbeat may be late before the monitor is down (push only)")
+	addMonitorCmd.Flags().BoolVar(&softAssertions, "soft-assertions", false, "Mark the monitor degraded instead of down when an assertion fails (http only)")
+	addMonitorCmd.Flags().DurationVar(&degradedThreshold, "degraded-threshold", 0, "Mark the monitor degraded when a check takes longer than this, 0 to disable")
+	addMonitorCmd.Flags().StringSliceVar(&alertChannels, "notify", nil, "Notification channels to alert, as named in the config file")
+	addMonitorCmd.Flags().StringSliceVar(&alertEvents, "notify-on", nil, "Events to alert on: down, recovery, cert_expiry (default all)")
+	addMonitorCmd.Flags().StringVar(&alertSeverity, "severity", "", "Severity of down alerts: critical, error, warning or info (default the channel's, or critical)")
+	addMonitorCmd.Flags().StringToStringVar(&alertRoutingKeys, "routing-key", nil, "PagerDuty routing key or Opsgenie API key to use instead of a channel's, as channel=key (repeatable)")
+	addMonitorCmd.Flags().DurationVar(&alertDelay, "alert-delay", 0, "How long the monitor must be down before alerting")
+	addMonitorCmd.Flags().DurationVar(&alertRepeat, "remind-every", 0, "Repeat the down alert this often until acknowledged, 0 to disable")
+	addMonitorCmd.Flags().StringSliceVar(&escalateChannels, "escalate-to", nil, "Notification channels to alert when a down alert isn't acknowledged in time")
+	addMonitorCmd.Flags().DurationVar(&escalateAfter, "escalate-after", 0, "How long after the down alert to escalate if it isn't acknowledged")
+	addMonitorCmd.Flags().Int64Var(&maxBodySize, "max-body-size", 0, "Maximum number of body bytes read for assertions (default 1MiB)")
+	addMonitorCmd.Flags().StringSliceVar(&monitorTags, "tag", nil, "Tags to group the monitor by, e.g. for maintenance windows")
+
+	var tagsMonitorCmd = &cobra.Command{
+		Use:   "tags [name] [tag...]",
+		Short: "Set a monitor's tags, leave them out to remove all tags",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 1 {
+				fmt.Println("Please provide a monitor name")
+				return
+			}
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var reply string
+			err = client.Call("Service.SetMonitorTags", struct {
+				Name string
+				Tags []string
+			}{args[0], args[1:]}, &reply)
+			if err != nil {
+				log.Fatalf("Error setting tags: %v", err)
+			}
+			fmt.Println(reply)
+		},
+	}
+
+	var alertsMonitorCmd = &cobra.Command{
+		Use:   "alerts [name]",
+		Short: "Set the notification channels and events a monitor alerts",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 1 {
+				fmt.Println("Please provide a monitor name")
+				return
+			}
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var reply string
+			err = client.Call("Service.SetMonitorAlerts", struct {
+				Name             string
+				Channels         []string
+				Events           []string
+				Severity         string
+				RoutingKeys      map[string]string
+				Delay            time.Duration
+				Repeat           time.Duration
+				EscalateAfter    time.Duration
+				EscalateChannels []string
+			}{args[0], alertChannels, alertEvents, alertSeverity, alertRoutingKeys,
+				alertDelay, alertRepeat, escalateAfter, escalateChannels}, &reply)
+			if err != nil {
+				log.Fatalf("Error setting alerts: %v", err)
+			}
+			fmt.Println(reply)
+		},
+	}
+
+	alertsMonitorCmd.Flags().StringSliceVar(&alertChannels, "notify", nil, "Notification channels to alert, leave out to disable alerts")
+	alertsMonitorCmd.Flags().StringSliceVar(&alertEvents, "notify-on", nil, "Events to alert on: down, recovery, cert_expiry (default all)")
+	alertsMonitorCmd.Flags().StringVar(&alertSeverity, "severity", "", "Severity of down alerts: critical, error, warning or info (default the channel's, or critical)")
+	alertsMonitorCmd.Flags().StringToStringVar(&alertRoutingKeys, "routing-key", nil, "PagerDuty routing key or Opsgenie API key to use instead of a channel's, as channel=key (repeatable)")
+	alertsMonitorCmd.Flags().DurationVar(&alertDelay, "alert-delay", 0, "How long the monitor must be down before alerting")
+	alertsMonitorCmd.Flags().DurationVar(&alertRepeat, "remind-every", 0, "Repeat the down alert this often until acknowledged, 0 to disable")
+	alertsMonitorCmd.Flags().StringSliceVar(&escalateChannels, "escalate-to", nil, "Notification channels to alert when a down alert isn't acknowledged in time")
+	alertsMonitorCmd.Flags().DurationVar(&escalateAfter, "escalate-after", 0, "How long after the down alert to escalate if it isn't acknowledged")
+
 	var removeMonitorCmd = &cobra.Command{
 		Use:   "remove [url]",
 		Short: "Remove a monitor",
@@ -194,7 +474,11 @@ func main() {
 					if !monitor.IsActive {
 						status = "paused"
 					}
-					fmt.Printf("- %s (%s) [%s]\n", monitor.Name, monitor.URL, status)
+					tags := ""
+					if len(monitor.Tags) > 0 {
+						tags = " tags: " + strings.Join(monitor.Tags, ",")
+					}
+					fmt.Printf("- %s (%s) [%s]%s\n", monitor.Name, monitor.URL, status, tags)
 				}
 			}
 		},
@@ -220,32 +504,609 @@ func main() {
 			defer client.Close()
 
 			var status types.ServiceStatus
-			err = client.Call("Service.GetServiceSta`--tus", args[0], &status)
+			err = client.Call("Service.GetServiceStatus", args[0], &status)
 			if err != nil {
 				log.Fatalf("Error getting monitor stats: %v", err)
 			}
 
 			fmt.Printf("Stats for %s (%s):\n", status.ServiceName, status.ServiceURL)
-			fmt.Printf("Status: %s\n", formatStatus(status.CurrentStatus))
+			fmt.Printf("Status: %s\n", formatStatus(status.Status))
+			if status.Maintenance != "" {
+				fmt.Printf("Maintenance: %s\n", status.Maintenance)
+			}
 			fmt.Printf("Current Response Time: %dms\n", status.ResponseTime)
+			if status.StatusCode != 0 {
+				fmt.Printf("Status Code: %d (%s)\n", status.StatusCode, status.Protocol)
+			}
+			if status.ResponseSize > 0 {
+				fmt.Printf("Response Size: %d bytes\n", status.ResponseSize)
+			}
+			if status.Protocol != "" {
+				fmt.Printf("Timings (current): %s\n", formatTimings(status.Timings))
+				fmt.Printf("Timings (average): %s\n", formatTimings(status.AvgTimings))
+			}
 			fmt.Printf("Average Response Time: %.2fms\n", status.AvgResponseTime)
 			fmt.Printf("Uptime (24h): %.2f%%\n", status.Uptime24Hours)
 			fmt.Printf("Uptime (30d): %.2f%%\n", status.Uptime30Days)
+			fmt.Printf("Degraded (24h): %.2f%%\n", status.Degraded24Hours)
+			fmt.Printf("Degraded (30d): %.2f%%\n", status.Degraded30Days)
 			if !status.CertificateExpiry.IsZero() {
 				fmt.Printf("Certificate Expires: %s\n", status.CertificateExpiry.Format("2006-01-02"))
 			}
+			if status.CertificateIssuer != "" {
+				fmt.Printf("Certificate Issuer: %s\n", status.CertificateIssuer)
+			}
+			if len(status.CertificateSANs) > 0 {
+				fmt.Printf("Certificate SANs: %s\n", strings.Join(status.CertificateSANs, ", "))
+			}
+			if status.TLSError != "" {
+				fmt.Printf("TLS Error: %s\n", status.TLSError)
+			}
+			if status.LastMessage != "" {
+				fmt.Printf("Message: %s\n", status.LastMessage)
+			}
+			if status.LastAnswer != "" {
+				fmt.Printf("Answer: %s\n", status.LastAnswer)
+			}
+			if len(status.Metrics) > 0 {
+				fmt.Printf("Metrics: %s\n", formatMetrics(status.Metrics))
+			}
+			if status.LastWarning != "" {
+				fmt.Printf("Warning: %s\n", status.LastWarning)
+			}
+			if status.LastError != "" {
+				fmt.Printf("Last Error: [%s] %s\n", status.LastErrorClass, status.LastError)
+			}
+		},
+	}
+
+	var historyMonitorCmd = &cobra.Command{
+		Use:   "history [name]",
+		Short: "Show recent checks for a monitor",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 1 {
+				fmt.Println("Please provide a monitor name")
+				return
+			}
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var monitors []types.Monitor
+			err = client.Call("Service.ListMonitors", struct{}{}, &monitors)
+			if err != nil {
+				log.Fatalf("Error listing monitors: %v", err)
+			}
+
+			monitorID, monitorType := -1, ""
+			for _, monitor := range monitors {
+				if monitor.Name == args[0] {
+					monitorID, monitorType = monitor.ID, monitor.Type
+				}
+			}
+			if monitorID < 0 {
+				log.Fatalf("Monitor %s not found", args[0])
+			}
+
+			var stats []types.HistoricalStat
+			err = client.Call("Service.GetHistoricalStats", struct {
+				MonitorID      int
+				Count          int
+				IncludeRetries bool
+			}{monitorID, historyCount, historyRetries}, &stats)
+			if err != nil {
+				log.Fatalf("Error getting monitor history: %v", err)
+			}
+
+			for _, stat := range stats {
+				status := formatStatus(stat.Status)
+				if stat.IsRetry {
+					status = fmt.Sprintf("RETRY %d", stat.Attempt)
+				}
+				detail := stat.Message
+				if stat.Warning != "" {
+					detail = stat.Warning
+				}
+				if stat.ErrorClass != "" {
+					detail = fmt.Sprintf("[%s] %s", stat.ErrorClass, stat.Error)
+				}
+				if stat.Answer != "" && detail == "" {
+					detail = stat.Answer
+				} else if stat.Answer != "" && monitorType == types.MonitorTypeDNS {
+					detail = fmt.Sprintf("%s (answer: %s)", detail, stat.Answer)
+				}
+				code := "-"
+				if stat.StatusCode != 0 {
+					code = fmt.Sprint(stat.StatusCode)
+				}
+				fmt.Printf("%s  %-8s %6dms  %3s %8s %8dB  %s\n", stat.Timestamp.Local().Format("2006-01-02 15:04:05"), status,
+					stat.ResponseTime, code, stat.Protocol, stat.ResponseSize, detail)
+			}
 		},
 	}
 
-	monitorCmd.AddCommand(addMonitorCmd, removeMonitorCmd, pauseMonitorCmd, resumeMonitorCmd, listMonitorsCmd, getMonitorCmd)
-	rootCmd.AddCommand(startDaemonCmd, monitorCmd)
+	historyMonitorCmd.Flags().IntVar(&historyCount, "count", 20, "Number of checks to show")
+	historyMonitorCmd.Flags().BoolVar(&historyRetries, "retries", false, "Include failed attempts that were retried")
+
+	monitorCmd.AddCommand(addMonitorCmd, alertsMonitorCmd, removeMonitorCmd, pauseMonitorCmd, resumeMonitorCmd, listMonitorsCmd, getMonitorCmd, historyMonitorCmd, tagsMonitorCmd)
+
+	var incidentCmd = &cobra.Command{
+		Use:   "incident",
+		Short: "Show incidents",
+	}
+
+	var listIncidentsCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List recent incidents",
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var incidents []types.Incident
+			err = client.Call("Service.ListIncidents", struct {
+				MonitorName string
+				Count       int
+				OpenOnly    bool
+			}{incidentMonitor, incidentCount, incidentOpen}, &incidents)
+			if err != nil {
+				log.Fatalf("Error listing incidents: %v", err)
+			}
+
+			if len(incidents) == 0 {
+				fmt.Println("No incidents")
+				return
+			}
+			for _, incident := range incidents {
+				ack := "   "
+				if !incident.AcknowledgedAt.IsZero() {
+					ack = "ACK"
+				}
+				fmt.Printf("#%-5d %-20s %s  %-16s %s %4d checks  [%s] %s\n", incident.ID, incident.MonitorName,
+					incident.StartedAt.Local().Format("2006-01-02 15:04:05"), formatIncidentDuration(incident),
+					ack, incident.CheckCount, incident.ErrorClass, incident.FirstError)
+			}
+		},
+	}
+
+	listIncidentsCmd.Flags().StringVar(&incidentMonitor, "monitor", "", "Only show incidents for this monitor")
+	listIncidentsCmd.Flags().IntVar(&incidentCount, "count", 20, "Number of incidents to show")
+	listIncidentsCmd.Flags().BoolVar(&incidentOpen, "open", false, "Only show ongoing incidents")
+
+	var getIncidentCmd = &cobra.Command{
+		Use:   "get [id]",
+		Short: "Show an incident and the checks recorded during it",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 1 {
+				fmt.Println("Please provide an incident ID")
+				return
+			}
+			id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+			if err != nil {
+				log.Fatalf("Invalid incident ID %q", args[0])
+			}
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var incident types.Incident
+			err = client.Call("Service.GetIncident", id, &incident)
+			if err != nil {
+				log.Fatalf("Error getting incident: %v", err)
+			}
+
+			fmt.Printf("Incident #%d for %s (%s):\n", incident.ID, incident.MonitorName, incident.MonitorURL)
+			fmt.Printf("Started: %s\n", incident.StartedAt.Local().Format("2006-01-02 15:04:05"))
+			if !incident.EndedAt.IsZero() {
+				fmt.Printf("Ended: %s\n", incident.EndedAt.Local().Format("2006-01-02 15:04:05"))
+			}
+			fmt.Printf("Duration: %s\n", formatIncidentDuration(incident))
+			fmt.Printf("Failed Checks: %d\n", incident.CheckCount)
+			if !incident.AlertedAt.IsZero() {
+				fmt.Printf("Alerted: %s\n", incident.AlertedAt.Local().Format("2006-01-02 15:04:05"))
+			}
+			if !incident.EscalatedAt.IsZero() {
+				fmt.Printf("Escalated: %s\n", incident.EscalatedAt.Local().Format("2006-01-02 15:04:05"))
+			}
+			if !incident.AcknowledgedAt.IsZero() {
+				fmt.Printf("Acknowledged: %s by %s\n", incident.AcknowledgedAt.Local().Format("2006-01-02 15:04:05"), incident.AcknowledgedBy)
+			}
+			fmt.Printf("First Error: [%s] %s\n", incident.ErrorClass, incident.FirstError)
+			if incident.LastError != incident.FirstError {
+				fmt.Printf("Last Error: %s\n", incident.LastError)
+			}
+			if len(incident.Checks) > 0 {
+				fmt.Println("Checks:")
+			}
+			for _, stat := range incident.Checks {
+				detail := stat.Error
+				if stat.ErrorClass != "" {
+					detail = fmt.Sprintf("[%s] %s", stat.ErrorClass, stat.Error)
+				}
+				fmt.Printf("  %s  %-8s %6dms  %s\n", stat.Timestamp.Local().Format("2006-01-02 15:04:05"),
+					formatStatus(stat.Status), stat.ResponseTime, detail)
+			}
+		},
+	}
+
+	incidentCmd.AddCommand(listIncidentsCmd, getIncidentCmd)
+
+	var alertCmd = &cobra.Command{
+		Use:   "alert",
+		Short: "Acknowledge alerts and show the alert history",
+	}
+
+	var ackAlertCmd = &cobra.Command{
+		Use:   "ack [monitor]",
+		Short: "Acknowledge a monitor's ongoing incident, stopping reminders and escalation",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 1 {
+				fmt.Println("Please provide a monitor name")
+				return
+			}
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var reply string
+			err = client.Call("Service.AcknowledgeAlert", struct {
+				MonitorName string
+				By          string
+			}{args[0], ackBy}, &reply)
+			if err != nil {
+				log.Fatalf("Error acknowledging alert: %v", err)
+			}
+			fmt.Println(reply)
+		},
+	}
+
+	ackAlertCmd.Flags().StringVar(&ackBy, "by", currentUser(), "Who is acknowledging the alert")
+
+	var listAlertsCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List recent alerts and acknowledgements",
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var alerts []types.Alert
+			err = client.Call("Service.ListAlerts", struct {
+				MonitorName string
+				Count       int
+			}{alertMonitor, alertCount}, &alerts)
+			if err != nil {
+				log.Fatalf("Error listing alerts: %v", err)
+			}
+
+			if len(alerts) == 0 {
+				fmt.Println("No alerts")
+				return
+			}
+			for _, alert := range alerts {
+				incident := ""
+				if alert.IncidentID != 0 {
+					incident = fmt.Sprintf("#%d", alert.IncidentID)
+				}
+				fmt.Printf("%s  %-20s %-6s %-12s %-20s %s\n", alert.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+					alert.MonitorName, incident, alert.Event, strings.Join(alert.Channels, ","), alert.Message)
+			}
+		},
+	}
+
+	listAlertsCmd.Flags().StringVar(&alertMonitor, "monitor", "", "Only show alerts for this monitor")
+	listAlertsCmd.Flags().IntVar(&alertCount, "count", 20, "Number of alerts to show")
+
+	alertCmd.AddCommand(ackAlertCmd, listAlertsCmd)
+
+	var notificationsCmd = &cobra.Command{
+		Use:     "notifications",
+		Aliases: []string{"notify"},
+		Short:   "Show notification deliveries and test channels",
+	}
+
+	var notificationLogCmd = &cobra.Command{
+		Use:   "log",
+		Short: "Show recent notifications and each attempt to deliver them",
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var notifications []types.Notification
+			err = client.Call("Service.ListNotifications", struct {
+				MonitorName string
+				Channel     string
+				Count       int
+			}{notificationMonitor, notificationChannel, notificationCount}, &notifications)
+			if err != nil {
+				log.Fatalf("Error listing notifications: %v", err)
+			}
+
+			if len(notifications) == 0 {
+				fmt.Println("No notifications")
+				return
+			}
+			for _, n := range notifications {
+				status := strings.ToUpper(n.Status)
+				if n.Status == types.NotificationPending && n.Attempts > 0 {
+					status += fmt.Sprintf(" (retry at %s)", n.NextAttemptAt.Local().Format("15:04:05"))
+				}
+				fmt.Printf("%s  %-20s %-12s %-12s %s\n", n.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+					n.MonitorName, n.Event, n.Channel, status)
+				for _, attempt := range n.Log {
+					result := "OK"
+					if attempt.Error != "" {
+						result = attempt.Error
+					}
+					code := "-"
+					if attempt.StatusCode != 0 {
+						code = strconv.Itoa(attempt.StatusCode)
+					}
+					fmt.Printf("  #%-2d %s  %3s %6dms  %s\n", attempt.Attempt, attempt.CreatedAt.Local().Format("15:04:05"),
+						code, attempt.Duration.Milliseconds(), result)
+				}
+			}
+		},
+	}
+
+	notificationLogCmd.Flags().StringVar(&notificationMonitor, "monitor", "", "Only show notifications for this monitor")
+	notificationLogCmd.Flags().StringVar(&notificationChannel, "channel", "", "Only show notifications sent to this channel")
+	notificationLogCmd.Flags().IntVar(&notificationCount, "count", 20, "Number of notifications to show")
+
+	var testNotificationCmd = &cobra.Command{
+		Use:   "test [channel] [monitor]",
+		Short: "Send a test notification for a monitor to a channel",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 2 {
+				fmt.Println("Please provide a channel and a monitor name")
+				return
+			}
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var reply string
+			err = client.Call("Service.TestNotification", struct {
+				Channel     string
+				MonitorName string
+			}{args[0], args[1]}, &reply)
+			if err != nil {
+				log.Fatalf("Error sending test notification: %v", err)
+			}
+			fmt.Println(reply)
+		},
+	}
+
+	notificationsCmd.AddCommand(notificationLogCmd, testNotificationCmd)
+
+	var maintenanceCmd = &cobra.Command{
+		Use:   "maintenance",
+		Short: "Manage maintenance windows",
+	}
+
+	var addMaintenanceCmd = &cobra.Command{
+		Use:   "add [name]",
+		Short: "Add a one-off or recurring maintenance window for monitors or tags",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 1 {
+				fmt.Println("Please provide a name for the maintenance window")
+				return
+			}
+			if maintenanceCron != "" && maintenanceWeekly != "" {
+				fmt.Println("Please provide either --cron or --weekly, not both")
+				return
+			}
+
+			location := time.Local
+			if maintenanceTimezone != "" {
+				var err error
+				if location, err = time.LoadLocation(maintenanceTimezone); err != nil {
+					log.Fatalf("Unknown timezone %q", maintenanceTimezone)
+				}
+			}
+			start, err := parseMaintenanceTime(maintenanceStart, location)
+			if err != nil {
+				log.Fatalf("Invalid start: %v", err)
+			}
+			end, err := parseMaintenanceTime(maintenanceEnd, location)
+			if err != nil {
+				log.Fatalf("Invalid end: %v", err)
+			}
+
+			schedule := maintenanceCron
+			if maintenanceWeekly != "" {
+				schedule = maintenanceWeekly
+			}
+
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var reply string
+			err = client.Call("Service.AddMaintenanceWindow", types.MaintenanceWindow{
+				Name:     args[0],
+				Monitors: maintenanceMonitors,
+				Tags:     maintenanceTags,
+				Mode:     maintenanceMode,
+				Start:    start,
+				End:      end,
+				Schedule: schedule,
+				Duration: maintenanceDuration,
+				Timezone: maintenanceTimezone,
+			}, &reply)
+			if err != nil {
+				log.Fatalf("Error adding maintenance window: %v", err)
+			}
+			fmt.Println(reply)
+		},
+	}
+
+	addMaintenanceCmd.Flags().StringSliceVar(&maintenanceMonitors, "monitor", nil, "Monitors the window applies to")
+	addMaintenanceCmd.Flags().StringSliceVar(&maintenanceTags, "tag", nil, "Apply the window to monitors with any of these tags")
+	addMaintenanceCmd.Flags().StringVar(&maintenanceMode, "mode", types.MaintenancePause, "pause to stop checks, or silence to keep checking without counting towards uptime or alerting")
+	addMaintenanceCmd.Flags().StringVar(&maintenanceStart, "start", "", "Start of a one-off window as \"2006-01-02 15:04\" (default now)")
+	addMaintenanceCmd.Flags().StringVar(&maintenanceEnd, "end", "", "End of a one-off window as \"2006-01-02 15:04\" (default start plus --duration)")
+	addMaintenanceCmd.Flags().DurationVar(&maintenanceDuration, "duration", 0, "How long the window lasts")
+	addMaintenanceCmd.Flags().StringVar(&maintenanceCron, "cron", "", "Cron expression the window recurs on, e.g. \"0 2 * * 6\"")
+	addMaintenanceCmd.Flags().StringVar(&maintenanceWeekly, "weekly", "", "Days and time the window recurs on every week, e.g. \"sat,sun 02:00\"")
+	addMaintenanceCmd.Flags().StringVar(&maintenanceTimezone, "timezone", "", "Timezone of the start, end and schedule, e.g. Europe/Berlin (default local)")
+
+	var listMaintenanceCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List maintenance windows",
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var windows []types.MaintenanceWindow
+			err = client.Call("Service.ListMaintenanceWindows", struct{}{}, &windows)
+			if err != nil {
+				log.Fatalf("Error listing maintenance windows: %v", err)
+			}
+
+			if len(windows) == 0 {
+				fmt.Println("No maintenance windows")
+				return
+			}
+			for _, w := range windows {
+				targets := slices.Clone(w.Monitors)
+				for _, tag := range w.Tags {
+					targets = append(targets, "tag:"+tag)
+				}
+
+				when := fmt.Sprintf("%s to %s", w.Start.Local().Format("2006-01-02 15:04"), w.End.Local().Format("2006-01-02 15:04"))
+				if w.Schedule != "" {
+					when = fmt.Sprintf("%s for %s", w.Schedule, w.Duration)
+					if w.Timezone != "" {
+						when += " " + w.Timezone
+					}
+				}
+
+				status := "ended"
+				switch {
+				case w.Active:
+					status = "ACTIVE"
+				case !w.NextStart.IsZero():
+					status = "next " + w.NextStart.Local().Format("2006-01-02 15:04")
+				}
+				fmt.Printf("%-20s %-8s %-40s %-30s %s\n", w.Name, w.Mode, when, strings.Join(targets, ","), status)
+			}
+		},
+	}
+
+	var removeMaintenanceCmd = &cobra.Command{
+		Use:   "remove [name]",
+		Short: "Remove a maintenance window",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 1 {
+				fmt.Println("Please provide the name of the maintenance window")
+				return
+			}
+			client, err := rpc.Dial("tcp", fmt.Sprintf("%s:%d", daemonHost, daemonPort))
+			if err != nil {
+				log.Fatalf("Error connecting to daemon: %v", err)
+			}
+			defer client.Close()
+
+			var reply string
+			err = client.Call("Service.RemoveMaintenanceWindow", args[0], &reply)
+			if err != nil {
+				log.Fatalf("Error removing maintenance window: %v", err)
+			}
+			fmt.Println(reply)
+		},
+	}
+
+	maintenanceCmd.AddCommand(addMaintenanceCmd, listMaintenanceCmd, removeMaintenanceCmd)
+	rootCmd.AddCommand(startDaemonCmd, monitorCmd, incidentCmd, alertCmd, notificationsCmd, maintenanceCmd)
 
 	rootCmd.Execute()
 }
 
-func formatStatus(isUp bool) string {
-	if isUp {
-		return "UP"
+func formatStatus(status string) string {
+	if status == "" {
+		return "UNKNOWN"
+	}
+	return strings.ToUpper(status)
+}
+
+// parseJSONAssertion turns an expression like "$.status == ok" into an
+// assertion. The value is optional for the exists operator.
+func parseJSONAssertion(expr string) (types.Assertion, error) {
+	fields := strings.Fields(expr)
+	if len(fields) < 2 {
+		return types.Assertion{}, fmt.Errorf("expected \"<path> <op> [value]\", got %q", expr)
+	}
+
+	assertion := types.Assertion{
+		Type: types.AssertJSON,
+		Path: fields[0],
+		Op:   fields[1],
+	}
+	if len(fields) > 2 {
+		assertion.Value = strings.Trim(strings.Join(fields[2:], " "), `"'`)
+	}
+
+	return assertion, nil
+}
+
+func formatTimings(t types.Timings) string {
+	return fmt.Sprintf("DNS %dms, Connect %dms, TLS %dms, TTFB %dms, Transfer %dms",
+		t.DNS.Milliseconds(), t.Connect.Milliseconds(), t.TLS.Milliseconds(), t.TTFB.Milliseconds(), t.Transfer.Milliseconds())
+}
+
+func formatMetrics(metrics []types.Metric) string {
+	parts := make([]string, len(metrics))
+	for i, metric := range metrics {
+		parts[i] = fmt.Sprintf("%s=%s%s", metric.Label, strconv.FormatFloat(metric.Value, 'f', -1, 64), metric.Unit)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatIncidentDuration(incident types.Incident) string {
+	duration := incident.Duration.Round(time.Second).String()
+	if incident.EndedAt.IsZero() {
+		return duration + " (ongoing)"
+	}
+	return duration
+}
+
+// currentUser names who is running the command, for acknowledgements
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "unknown"
+}
+
+// parseMaintenanceTime parses a maintenance window's start or end in the
+// window's timezone, or returns the zero time when it isn't given
+func parseMaintenanceTime(value string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
 	}
-	return "DOWN"
+	return time.ParseInLocation("2006-01-02 15:04", value, location)
 }
@@ -0,0 +1,292 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/watzon/go-up/internal/notify"
+	"github.com/watzon/go-up/internal/types"
+)
+
+// recentChecks is how many recent checks are sent along with an event
+const recentChecks = 10
+
+// defaultAlertEvents are sent when a monitor doesn't list its own events
+var defaultAlertEvents = []string{types.EventDown, types.EventRecovery, types.EventCertExpiry}
+
+func alertEvents(m types.Monitor) []string {
+	if len(m.AlertEvents) > 0 {
+		return m.AlertEvents
+	}
+	return defaultAlertEvents
+}
+
+func (s *Service) validateAlerts(m types.Monitor) error {
+	channels := append(slices.Clone(m.AlertChannels), m.EscalateChannels...)
+	for _, name := range channels {
+		if !s.notifier.Has(name) {
+			return fmt.Errorf("unknown notification channel %q", name)
+		}
+	}
+	// A routing key for one channel type is a credential for another
+	for name := range m.AlertRoutingKeys {
+		if !slices.ContainsFunc(channels, func(channel string) bool { return strings.EqualFold(channel, name) }) {
+			return fmt.Errorf("routing key set for %s, which the monitor doesn't alert", name)
+		}
+		if t := s.notifier.Type(name); t != "pagerduty" && t != "opsgenie" {
+			return fmt.Errorf("routing keys can only be set for pagerduty and opsgenie channels, %s is a %s channel", name, t)
+		}
+	}
+	for _, event := range m.AlertEvents {
+		if !slices.Contains(defaultAlertEvents, event) {
+			return fmt.Errorf("unknown alert event %q", event)
+		}
+	}
+	if m.AlertDelay < 0 || m.AlertRepeat < 0 || m.EscalateAfter < 0 {
+		return fmt.Errorf("alert delay, reminder and escalation times must not be negative")
+	}
+	if len(m.EscalateChannels) > 0 && len(m.AlertChannels) == 0 {
+		return fmt.Errorf("escalation channels need alert channels to escalate from")
+	}
+	if len(m.EscalateChannels) > 0 && m.EscalateAfter == 0 {
+		return fmt.Errorf("escalation channels need a time to escalate after")
+	}
+	return notify.ValidateSeverity(m.AlertSeverity)
+}
+
+// alert applies the monitor's alert policy after a check is recorded.
+// Down alerts wait until the incident is older than the alert delay, then
+// reminders repeat and the incident escalates until it is acknowledged.
+// Recoveries are only sent for incidents that were alerted on, and are the
+// only alerts sent during maintenance.
+func (s *Service) alert(m types.Monitor, result types.CheckResult, change types.StateChange) {
+	if len(m.AlertChannels) == 0 || result.IsRetry {
+		return
+	}
+	events := alertEvents(m)
+	if result.Maintenance {
+		events = slices.DeleteFunc(slices.Clone(events), func(event string) bool { return event != types.EventRecovery })
+	}
+
+	if incident := change.Incident; incident != nil {
+		switch {
+		case incident.EndedAt.IsZero() && slices.Contains(events, types.EventDown):
+			s.alertDown(m, result, change)
+		case !incident.EndedAt.IsZero() && !incident.AlertedAt.IsZero() && slices.Contains(events, types.EventRecovery):
+			event := s.newEvent(m, result, change, types.EventRecovery)
+			event.Message = fmt.Sprintf("%s is %s again after %s", m.Name, change.To, incident.Duration.Round(time.Second))
+			s.send(incidentChannels(m, *incident), event)
+		}
+	}
+
+	switch s.certEvent(m, result) {
+	case types.EventCertExpiry:
+		if slices.Contains(events, types.EventCertExpiry) {
+			event := s.newEvent(m, result, change, types.EventCertExpiry)
+			event.Message = fmt.Sprintf("%s: %s", m.Name, describeExpiry(result.CertExpiry))
+			s.send(m.AlertChannels, event)
+		}
+	case types.EventCertRenewed:
+		// Like recoveries, sent during maintenance too
+		if slices.Contains(alertEvents(m), types.EventCertExpiry) {
+			event := s.newEvent(m, result, change, types.EventCertRenewed)
+			event.Message = fmt.Sprintf("%s: certificate renewed, now expires on %s", m.Name, result.CertExpiry.Format("2006-01-02"))
+			s.send(m.AlertChannels, event)
+		}
+	}
+}
+
+// alertDown sends the down alert once the incident has lasted the alert
+// delay, then any reminders and escalation that are due
+func (s *Service) alertDown(m types.Monitor, result types.CheckResult, change types.StateChange) {
+	incident := *change.Incident
+	now := time.Now()
+	down := now.Sub(incident.StartedAt).Round(time.Second)
+
+	if incident.AlertedAt.IsZero() {
+		if down < m.AlertDelay {
+			return
+		}
+		event := s.newEvent(m, result, change, types.EventDown)
+		event.Message = fmt.Sprintf("%s is down: %s", m.Name, result.Error)
+		if m.AlertDelay > 0 {
+			event.Message = fmt.Sprintf("%s has been down for %s: %s", m.Name, down, result.Error)
+		}
+		s.send(m.AlertChannels, event)
+		return
+	}
+	if !incident.AcknowledgedAt.IsZero() {
+		return
+	}
+
+	if len(m.EscalateChannels) > 0 && incident.EscalatedAt.IsZero() && now.Sub(incident.AlertedAt) >= m.EscalateAfter {
+		event := s.newEvent(m, result, change, types.EventEscalation)
+		event.Message = fmt.Sprintf("%s has been down for %s without being acknowledged: %s", m.Name, down, result.Error)
+		s.send(m.EscalateChannels, event)
+		return
+	}
+	if m.AlertRepeat > 0 && now.Sub(incident.LastAlertAt) >= m.AlertRepeat {
+		event := s.newEvent(m, result, change, types.EventReminder)
+		event.Message = fmt.Sprintf("%s is still down after %s: %s", m.Name, down, result.Error)
+		s.send(incidentChannels(m, incident), event)
+	}
+}
+
+// incidentChannels returns the channels that have been alerted about an
+// incident, including the escalation channels once it has escalated
+func incidentChannels(m types.Monitor, incident types.Incident) []string {
+	if incident.EscalatedAt.IsZero() {
+		return m.AlertChannels
+	}
+	channels := slices.Clone(m.AlertChannels)
+	for _, name := range m.EscalateChannels {
+		if !slices.Contains(channels, name) {
+			channels = append(channels, name)
+		}
+	}
+	return channels
+}
+
+// send queues an event for delivery to the channels and records it in the
+// alert history
+func (s *Service) send(channels []string, event notify.Event) {
+	if err := s.outbox.queue(channels, event); err != nil {
+		log.Printf("Error queueing %s notification for %s: %v", event.Type, event.Monitor, err)
+		return
+	}
+	if err := s.db.RecordAlert(event.MonitorID, event.IncidentID, event.Type, channels, event.Message); err != nil {
+		log.Printf("Error recording %s alert for %s: %v", event.Type, event.Monitor, err)
+	}
+}
+
+// newEventID returns a random ID for an event, which receivers can use to
+// ignore repeated deliveries
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// testEvent builds a test event for a monitor from its latest status and
+// ongoing incident, so templates can be tried against real data
+func (s *Service) testEvent(m types.Monitor) (notify.Event, error) {
+	status, err := s.db.GetStats(m.Name, 24*time.Hour)
+	if err != nil {
+		return notify.Event{}, err
+	}
+	result := types.CheckResult{
+		ResponseTime: time.Duration(status.ResponseTime) * time.Millisecond,
+		Status:       status.Status,
+		CertExpiry:   status.CertificateExpiry,
+	}
+	if status.Status != types.StatusUp {
+		result.Error = status.LastError
+		result.ErrorClass = status.LastErrorClass
+	}
+
+	var change types.StateChange
+	incidents, err := s.db.ListIncidents(m.Name, 1, true)
+	if err != nil {
+		return notify.Event{}, err
+	}
+	if len(incidents) > 0 {
+		change.Incident = &incidents[0]
+	}
+
+	event := s.newEvent(m, result, change, types.EventTest)
+	if status.Status == "" {
+		event.Message = fmt.Sprintf("Test notification for %s, which hasn't been checked yet", m.Name)
+	} else {
+		event.Message = fmt.Sprintf("Test notification for %s, which is %s", m.Name, status.Status)
+	}
+	return event, nil
+}
+
+// certEvent returns the certificate event a check calls for: cert_expiry
+// when it found a certificate inside the monitor's warning or down threshold
+// that hasn't been alerted about yet, cert_renewed when the certificate
+// alerted about has been replaced by one outside it, or nothing
+func (s *Service) certEvent(m types.Monitor, result types.CheckResult) string {
+	if result.CertExpiry.IsZero() {
+		return ""
+	}
+
+	// Stored with the monitor so a restart doesn't alert again
+	alerted, err := s.db.CertAlerted(m.ID)
+	if err != nil {
+		log.Printf("Error getting certificate alert for %s: %v", m.Name, err)
+		return ""
+	}
+
+	threshold := max(m.CertWarnDays, m.CertDownDays)
+	var eventType string
+	var expiry *time.Time
+	switch {
+	case threshold == 0 || time.Until(result.CertExpiry) >= days(threshold):
+		if alerted.IsZero() {
+			return ""
+		}
+		eventType = types.EventCertRenewed
+	case alerted.Equal(result.CertExpiry):
+		return ""
+	default:
+		eventType, expiry = types.EventCertExpiry, &result.CertExpiry
+	}
+
+	if err := s.db.SetCertAlerted(m.ID, expiry); err != nil {
+		log.Printf("Error recording certificate alert for %s: %v", m.Name, err)
+	}
+	return eventType
+}
+
+func (s *Service) newEvent(m types.Monitor, result types.CheckResult, change types.StateChange, eventType string) notify.Event {
+	event := notify.Event{
+		ID:             newEventID(),
+		Type:           eventType,
+		MonitorID:      m.ID,
+		Monitor:        m.Name,
+		URL:            m.URL,
+		Status:         result.Status,
+		PreviousStatus: change.From,
+		Error:          result.Error,
+		ErrorClass:     result.ErrorClass,
+		ResponseTime:   int(result.ResponseTime.Milliseconds()),
+		Severity:       m.AlertSeverity,
+		RoutingKeys:    m.AlertRoutingKeys,
+		LastError:      result.Error,
+		Time:           time.Now(),
+		MonitorConfig:  m,
+	}
+	if !result.CertExpiry.IsZero() {
+		event.CertExpiry = &result.CertExpiry
+	}
+	if change.Incident != nil {
+		event.IncidentID = change.Incident.ID
+		event.IncidentDuration = change.Incident.Duration
+		event.LastError = change.Incident.LastError
+	}
+
+	recent, err := s.db.GetHistoricalStats(m.ID, recentChecks, false)
+	if err != nil {
+		log.Printf("Error getting recent checks for %s: %v", m.Name, err)
+	}
+	event.Recent = recent
+
+	status, err := s.db.GetStats(m.Name, 24*time.Hour)
+	if err != nil {
+		log.Printf("Error getting stats for %s: %v", m.Name, err)
+	}
+	event.Uptime24Hours = status.Uptime24Hours
+	event.Uptime30Days = status.Uptime30Days
+	event.ServiceStatus = status
+	if event.LastError == "" {
+		event.LastError = status.LastError
+	}
+
+	return event
+}
@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+const defaultMaxBodySize = 1 << 20
+
+func maxBodySize(m types.Monitor) int64 {
+	if m.MaxBodySize > 0 {
+		return m.MaxBodySize
+	}
+	return defaultMaxBodySize
+}
+
+func readBody(body io.Reader, limit int64) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(body, limit))
+}
+
+// evaluateAssertions runs every assertion against the response body and
+// returns a description of the first failing assertion, or of the first
+// failing soft assertion as a warning when all the others pass
+func evaluateAssertions(assertions []types.Assertion, body []byte) (failure, warning string) {
+	for _, a := range assertions {
+		err := evaluateAssertion(a, body)
+		switch {
+		case err == nil:
+		case !a.Soft:
+			return err.Error(), ""
+		case warning == "":
+			warning = err.Error()
+		}
+	}
+	return "", warning
+}
+
+func evaluateAssertion(a types.Assertion, body []byte) error {
+	switch a.Type {
+	case types.AssertContains:
+		if !strings.Contains(string(body), a.Value) {
+			return fmt.Errorf("body does not contain %q", a.Value)
+		}
+	case types.AssertNotContains:
+		if strings.Contains(string(body), a.Value) {
+			return fmt.Errorf("body contains %q", a.Value)
+		}
+	case types.AssertRegex:
+		re, err := regexp.Compile(a.Value)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %v", a.Value, err)
+		}
+		if !re.Match(body) {
+			return fmt.Errorf("body does not match /%s/", a.Value)
+		}
+	case types.AssertJSON:
+		return evaluateJSONAssertion(a, body)
+	default:
+		return fmt.Errorf("unknown assertion type %q", a.Type)
+	}
+	return nil
+}
+
+func validateAssertions(assertions []types.Assertion) error {
+	for _, a := range assertions {
+		switch a.Type {
+		case types.AssertContains, types.AssertNotContains:
+		case types.AssertRegex:
+			if _, err := regexp.Compile(a.Value); err != nil {
+				return fmt.Errorf("invalid regex %q: %w", a.Value, err)
+			}
+		case types.AssertJSON:
+			if err := validateJSONAssertion(a); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown assertion type %q", a.Type)
+		}
+	}
+	return nil
+}
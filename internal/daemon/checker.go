@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+const (
+	defaultInterval = 60 * time.Second
+	defaultTimeout  = 10 * time.Second
+
+	defaultRetryInterval = 10 * time.Second
+)
+
+// checker runs a single check against a monitor
+type checker func(m types.Monitor) types.CheckResult
+
+// checkers maps each monitor type to the function that checks it
+var checkers = map[string]checker{
+	types.MonitorTypeHTTP: checkHTTP,
+	types.MonitorTypeTCP:  checkTCP,
+	types.MonitorTypeDNS:  checkDNS,
+	types.MonitorTypeExec: checkExec,
+	types.MonitorTypeGRPC: checkGRPC,
+}
+
+func checkService(m types.Monitor) types.CheckResult {
+	check, ok := checkers[m.Type]
+	if !ok {
+		check = checkHTTP
+	}
+	result := check(m)
+	setStatus(m, &result)
+	return result
+}
+
+// setStatus decides whether a check is up, degraded or down. An up check is
+// degraded when it has a warning or was slower than the monitor's degraded
+// threshold.
+func setStatus(m types.Monitor, result *types.CheckResult) {
+	if !result.IsUp {
+		result.Status = types.StatusDown
+		return
+	}
+
+	if m.DegradedThreshold > 0 && result.ResponseTime > m.DegradedThreshold {
+		warn(result, fmt.Sprintf("response time %s exceeds %s", result.ResponseTime.Round(time.Millisecond), m.DegradedThreshold))
+	}
+
+	result.Status = types.StatusUp
+	if result.Warning != "" {
+		result.Status = types.StatusDegraded
+	}
+}
+
+func monitorInterval(m types.Monitor) time.Duration {
+	if m.Interval > 0 {
+		return m.Interval
+	}
+	return defaultInterval
+}
+
+// checkInterval is how often the scheduler runs a monitor. Push monitors
+// aren't polled, they are checked for missed heartbeats more often than
+// their interval so a missed one is noticed quickly.
+func checkInterval(m types.Monitor) time.Duration {
+	interval := monitorInterval(m)
+	if m.Type == types.MonitorTypePush && interval > pushCheckInterval {
+		return pushCheckInterval
+	}
+	return interval
+}
+
+func monitorRetryInterval(m types.Monitor) time.Duration {
+	if m.RetryInterval > 0 {
+		return m.RetryInterval
+	}
+	return defaultRetryInterval
+}
+
+func monitorTimeout(m types.Monitor) time.Duration {
+	if m.Timeout > 0 {
+		return m.Timeout
+	}
+	return defaultTimeout
+}
+
+func validateMonitor(m types.Monitor, allowExec bool) error {
+	if _, ok := checkers[m.Type]; !ok && m.Type != types.MonitorTypePush {
+		return fmt.Errorf("unknown monitor type %q", m.Type)
+	}
+	if m.Interval != 0 && m.Interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s, got %s", m.Interval)
+	}
+	if m.Retries < 0 {
+		return fmt.Errorf("retries must not be negative, got %d", m.Retries)
+	}
+	if m.RetryInterval != 0 && m.RetryInterval < time.Second {
+		return fmt.Errorf("retry interval must be at least 1s, got %s", m.RetryInterval)
+	}
+	if m.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative, got %s", m.Timeout)
+	}
+	if m.DegradedThreshold < 0 {
+		return fmt.Errorf("degraded threshold must not be negative, got %s", m.DegradedThreshold)
+	}
+	if m.MaxBodySize < 0 {
+		return fmt.Errorf("max body size must not be negative, got %d", m.MaxBodySize)
+	}
+	if err := validateAssertions(m.Assertions); err != nil {
+		return err
+	}
+
+	switch m.Type {
+	case types.MonitorTypeHTTP:
+		return validateHTTPMonitor(m)
+	case types.MonitorTypeTCP:
+		return validateTCPAddress(m.URL)
+	case types.MonitorTypeDNS:
+		return validateDNSMonitor(m)
+	case types.MonitorTypeGRPC:
+		return validateGRPCMonitor(m)
+	case types.MonitorTypeExec:
+		return validateExecMonitor(m, allowExec)
+	case types.MonitorTypePush:
+		return validatePushMonitor(m)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+var dnsRecordTypes = map[string]bool{
+	"A":     true,
+	"AAAA":  true,
+	"CNAME": true,
+	"MX":    true,
+	"TXT":   true,
+}
+
+func checkDNS(m types.Monitor) (result types.CheckResult) {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), monitorTimeout(m))
+	defer cancel()
+
+	answers, err := resolveDNS(ctx, newResolver(m.DNSResolver), m.URL, dnsRecordType(m))
+	result.ResponseTime = time.Since(start)
+	if err != nil {
+		failWithError(&result, err)
+		return
+	}
+
+	result.Answer = strings.Join(answers, ", ")
+	switch {
+	case len(answers) == 0:
+		fail(&result, types.ErrorClassDNS, fmt.Sprintf("no %s records found", dnsRecordType(m)))
+	case len(m.DNSExpected) > 0 && !sameAnswers(answers, m.DNSExpected, dnsRecordType(m)):
+		fail(&result, types.ErrorClassUnexpected, fmt.Sprintf("answer %s does not match expected %s", result.Answer, strings.Join(m.DNSExpected, ", ")))
+	default:
+		result.IsUp = true
+	}
+	return
+}
+
+func dnsRecordType(m types.Monitor) string {
+	if m.DNSRecordType == "" {
+		return "A"
+	}
+	return strings.ToUpper(m.DNSRecordType)
+}
+
+// newResolver returns a resolver that sends its queries to addr, or the
+// system resolver when addr is empty
+func newResolver(addr string) *net.Resolver {
+	if addr == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+func resolveDNS(ctx context.Context, resolver *net.Resolver, host, recordType string) ([]string, error) {
+	var answers []string
+
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := resolver.LookupIP(ctx, network, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, cname)
+	case "MX":
+		records, err := resolver.LookupMX(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range records {
+			answers = append(answers, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+	case "TXT":
+		records, err := resolver.LookupTXT(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, records...)
+	default:
+		return nil, fmt.Errorf("unsupported DNS record type %q", recordType)
+	}
+
+	for i, answer := range answers {
+		answers[i] = normalizeAnswer(answer, recordType)
+	}
+	sort.Strings(answers)
+
+	return answers, nil
+}
+
+// sameAnswers reports whether the resolved answers and the expected values
+// contain exactly the same records, ignoring order
+func sameAnswers(answers, expected []string, recordType string) bool {
+	want := make(map[string]bool, len(expected))
+	for _, e := range expected {
+		want[normalizeAnswer(e, recordType)] = true
+	}
+
+	got := make(map[string]bool, len(answers))
+	for _, a := range answers {
+		if !want[a] {
+			return false
+		}
+		got[a] = true
+	}
+
+	return len(got) == len(want)
+}
+
+// normalizeAnswer makes names comparable by trimming the root dot and
+// lowercasing them. TXT records are case sensitive and left untouched.
+func normalizeAnswer(answer, recordType string) string {
+	answer = strings.TrimSpace(answer)
+	if recordType == "TXT" {
+		return answer
+	}
+	return strings.TrimSuffix(strings.ToLower(answer), ".")
+}
+
+func validateDNSMonitor(m types.Monitor) error {
+	if m.URL == "" || strings.Contains(m.URL, "/") {
+		return fmt.Errorf("invalid DNS name %q", m.URL)
+	}
+	if !dnsRecordTypes[dnsRecordType(m)] {
+		return fmt.Errorf("unsupported DNS record type %q", m.DNSRecordType)
+	}
+	return nil
+}
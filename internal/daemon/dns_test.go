@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startDNSServer answers queries over UDP from a fixed set of records, keyed
+// by name and type, until the test ends. Names without records get NXDOMAIN.
+func startDNSServer(t *testing.T, records map[dnsmessage.Question][]dnsmessage.Resource) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			q := query.Questions[0]
+
+			reply := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true, RCode: dnsmessage.RCodeNameError},
+				Questions: query.Questions,
+			}
+			for question, answers := range records {
+				if question.Name == q.Name {
+					reply.RCode = dnsmessage.RCodeSuccess
+					if question.Type == q.Type {
+						reply.Answers = answers
+					}
+				}
+			}
+
+			packed, err := reply.Pack()
+			if err != nil {
+				t.Errorf("packing reply: %v", err)
+				return
+			}
+			conn.WriteTo(packed, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func question(name string, qtype dnsmessage.Type) dnsmessage.Question {
+	return dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}
+}
+
+func resource(name string, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Class: dnsmessage.ClassINET, TTL: 60},
+		Body:   body,
+	}
+}
+
+func TestCheckDNS(t *testing.T) {
+	resolver := startDNSServer(t, map[dnsmessage.Question][]dnsmessage.Resource{
+		question("example.test.", dnsmessage.TypeA): {
+			resource("example.test.", &dnsmessage.AResource{A: [4]byte{192, 0, 2, 10}}),
+			resource("example.test.", &dnsmessage.AResource{A: [4]byte{192, 0, 2, 11}}),
+		},
+		question("example.test.", dnsmessage.TypeMX): {
+			resource("example.test.", &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mail.example.test.")}),
+		},
+		question("example.test.", dnsmessage.TypeTXT): {
+			resource("example.test.", &dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}}),
+		},
+	})
+
+	tests := []struct {
+		name       string
+		host       string
+		recordType string
+		expected   []string
+		wantUp     bool
+		wantAnswer string
+		wantClass  string
+	}{
+		{name: "A", host: "example.test", recordType: "A", wantUp: true, wantAnswer: "192.0.2.10, 192.0.2.11"},
+		{name: "A in any order", host: "example.test", recordType: "A", expected: []string{"192.0.2.11", "192.0.2.10"}, wantUp: true, wantAnswer: "192.0.2.10, 192.0.2.11"},
+		{name: "A mismatch", host: "example.test", recordType: "A", expected: []string{"192.0.2.99"}, wantAnswer: "192.0.2.10, 192.0.2.11", wantClass: types.ErrorClassUnexpected},
+		{name: "MX", host: "example.test", recordType: "MX", expected: []string{"10 mail.example.test."}, wantUp: true, wantAnswer: "10 mail.example.test"},
+		{name: "TXT", host: "example.test", recordType: "TXT", wantUp: true, wantAnswer: "v=spf1 -all"},
+		{name: "NXDOMAIN", host: "missing.test", recordType: "A", wantClass: types.ErrorClassDNS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checkDNS(types.Monitor{
+				Type:          types.MonitorTypeDNS,
+				URL:           tt.host,
+				DNSRecordType: tt.recordType,
+				DNSResolver:   resolver,
+				DNSExpected:   tt.expected,
+				Timeout:       2 * time.Second,
+			})
+			if result.IsUp != tt.wantUp {
+				t.Errorf("IsUp = %v, want %v (error: %s)", result.IsUp, tt.wantUp, result.Error)
+			}
+			if result.Answer != tt.wantAnswer {
+				t.Errorf("Answer = %q, want %q", result.Answer, tt.wantAnswer)
+			}
+			if result.ErrorClass != tt.wantClass {
+				t.Errorf("ErrorClass = %q, want %q (error: %s)", result.ErrorClass, tt.wantClass, result.Error)
+			}
+		})
+	}
+}
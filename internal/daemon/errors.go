@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// classifyError maps an error returned while checking a monitor to one of
+// the error classes in the types package
+func classifyError(err error) string {
+	var (
+		dnsErr      *net.DNSError
+		netErr      net.Error
+		verifyErr   *tls.CertificateVerificationError
+		recordErr   tls.RecordHeaderError
+		authorityEr x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidErr  x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return types.ErrorClassDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return types.ErrorClassTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return types.ErrorClassConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return types.ErrorClassConnectionReset
+	case errors.As(err, &verifyErr), errors.As(err, &recordErr), errors.As(err, &authorityEr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return types.ErrorClassTLS
+	}
+	return types.ErrorClassConnection
+}
+
+// fail marks the result as down with the given error class and message
+func fail(result *types.CheckResult, class, message string) {
+	result.IsUp = false
+	result.ErrorClass = class
+	result.Error = message
+}
+
+// failWithError marks the result as down, classifying err
+func failWithError(result *types.CheckResult, err error) {
+	fail(result, classifyError(err), err.Error())
+}
+
+// warn adds a warning to the result, which marks an up check degraded
+func warn(result *types.CheckResult, message string) {
+	if message == "" {
+		return
+	}
+	if result.Warning != "" {
+		message = result.Warning + "; " + message
+	}
+	result.Warning = message
+}
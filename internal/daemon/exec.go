@@ -0,0 +1,214 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// Exit codes used by Nagios-compatible plugins
+const (
+	execOK       = 0
+	execWarning  = 1
+	execCritical = 2
+)
+
+// errExecDisabled is returned for exec monitors unless the daemon allows them
+var errExecDisabled = errors.New("exec monitors are disabled, set daemon.allow_exec to enable them")
+
+// maxExecOutputSize caps how much of a command's stdout is kept
+const maxExecOutputSize = 64 * 1024
+
+// checkExec runs the monitor's command through the shell. Exit code 0 is up,
+// 1 is degraded and anything else is down. The first line of
+// stdout is the status message and perfdata after a '|' is kept as metrics.
+func checkExec(m types.Monitor) (result types.CheckResult) {
+	timeout := monitorTimeout(m)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr limitedBuffer
+	stdout.limit, stderr.limit = maxExecOutputSize, maxExecOutputSize
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", m.URL)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait on pipes held open by children of a killed command
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	result.ResponseTime = time.Since(start)
+
+	message, metrics := parsePluginOutput(stdout.String())
+	result.Message = message
+	result.Metrics = metrics
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		fail(&result, types.ErrorClassTimeout, fmt.Sprintf("command timed out after %s", timeout))
+		return
+	case err != nil && !errors.As(err, &exitErr):
+		fail(&result, types.ErrorClassConfig, err.Error())
+		return
+	}
+
+	switch code := cmd.ProcessState.ExitCode(); code {
+	case execOK:
+		result.IsUp = true
+	case execWarning:
+		result.IsUp = true
+		if message == "" {
+			message = "command exited with status 1"
+		}
+		warn(&result, message)
+	default:
+		if message == "" {
+			message = firstLine(stderr.String())
+		}
+		switch {
+		case message == "":
+			message = fmt.Sprintf("command exited with status %d", code)
+		case code != execCritical:
+			message = fmt.Sprintf("%s (exit status %d)", message, code)
+		}
+		fail(&result, types.ErrorClassCommand, message)
+	}
+	return
+}
+
+// parsePluginOutput splits plugin output into its status message and
+// perfdata. Perfdata can follow a '|' on the first line, and on later lines
+// after a '|' everything that follows is perfdata.
+func parsePluginOutput(output string) (string, []types.Metric) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+
+	message, perfdata, _ := strings.Cut(lines[0], "|")
+	perf := []string{perfdata}
+	for i, line := range lines[1:] {
+		if _, after, ok := strings.Cut(line, "|"); ok {
+			perf = append(perf, after)
+			perf = append(perf, lines[i+2:]...)
+			break
+		}
+	}
+
+	var metrics []types.Metric
+	for _, p := range perf {
+		metrics = append(metrics, parsePerfdata(p)...)
+	}
+	return strings.TrimSpace(message), metrics
+}
+
+// parsePerfdata parses space separated 'label'=value[UOM];[warn];[crit];[min];[max]
+// entries, skipping any that are malformed
+func parsePerfdata(s string) []types.Metric {
+	var metrics []types.Metric
+	for _, entry := range splitPerfdata(s) {
+		label, data, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		label = strings.Trim(label, "'")
+
+		fields := strings.Split(data, ";")
+		value, unit := splitUnit(fields[0])
+		v, err := strconv.ParseFloat(value, 64)
+		if label == "" || err != nil {
+			continue
+		}
+
+		metric := types.Metric{Label: label, Value: v, Unit: unit}
+		for i, f := range fields[1:] {
+			switch i {
+			case 0:
+				metric.Warn = f
+			case 1:
+				metric.Crit = f
+			case 2:
+				metric.Min = f
+			case 3:
+				metric.Max = f
+			}
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+// splitPerfdata splits perfdata on spaces outside of single quoted labels
+func splitPerfdata(s string) []string {
+	var entries []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t') && !quoted:
+			if current.Len() > 0 {
+				entries = append(entries, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		entries = append(entries, current.String())
+	}
+	return entries
+}
+
+// splitUnit splits a perfdata value like "12.5ms" into "12.5" and "ms"
+func splitUnit(s string) (string, string) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r == '.' || r == '-' || r == '+' || r == 'e' || r == 'E')
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
+
+func validateExecMonitor(m types.Monitor, allowExec bool) error {
+	if !allowExec {
+		return errExecDisabled
+	}
+	if strings.TrimSpace(m.URL) == "" {
+		return fmt.Errorf("command must not be empty")
+	}
+	return nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest, so a noisy command can't grow the daemon's memory
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
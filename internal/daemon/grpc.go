@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// checkGRPC calls grpc.health.v1.Health/Check for the monitor's service.
+// Only a SERVING response is up.
+func checkGRPC(m types.Monitor) (result types.CheckResult) {
+	host, _, _ := net.SplitHostPort(m.URL)
+
+	creds := insecure.NewCredentials()
+	var inspector *tlsInspector
+	if m.GRPCTLS {
+		var err error
+		inspector, err = newTLSInspector(m, host)
+		if err != nil {
+			fail(&result, types.ErrorClassConfig, err.Error())
+			return
+		}
+		creds = credentials.NewTLS(inspector.config())
+	}
+
+	conn, err := grpc.NewClient(m.URL, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		fail(&result, types.ErrorClassConfig, err.Error())
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), monitorTimeout(m))
+	defer cancel()
+
+	start := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: m.GRPCService})
+	result.ResponseTime = time.Since(start)
+	if inspector != nil {
+		defer inspector.apply(m, &result)
+	}
+
+	if err != nil {
+		fail(&result, classifyGRPCError(err), status.Convert(err).Message())
+		return
+	}
+
+	serving := resp.GetStatus()
+	result.Message = serving.String()
+	if serving != healthpb.HealthCheckResponse_SERVING {
+		fail(&result, types.ErrorClassGRPCStatus, fmt.Sprintf("service is %s", serving))
+		return
+	}
+
+	result.IsUp = true
+	return
+}
+
+// classifyGRPCError maps a failed health check call to an error class. The
+// transport error is only available as text in the status message.
+func classifyGRPCError(err error) string {
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		return types.ErrorClassTimeout
+	case codes.Unavailable:
+		message := status.Convert(err).Message()
+		switch {
+		case strings.Contains(message, "connection refused"):
+			return types.ErrorClassConnectionRefused
+		case strings.Contains(message, "connection reset"):
+			return types.ErrorClassConnectionReset
+		case strings.Contains(message, "no such host"), strings.Contains(message, "produced zero addresses"):
+			return types.ErrorClassDNS
+		case strings.Contains(message, "handshake"):
+			return types.ErrorClassTLS
+		}
+		return types.ErrorClassConnection
+	case codes.Unimplemented:
+		return types.ErrorClassUnexpected
+	}
+	return types.ErrorClassGRPCStatus
+}
+
+func validateGRPCMonitor(m types.Monitor) error {
+	return validateTCPAddress(m.URL)
+}
@@ -0,0 +1,187 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// defaultAcceptedStatus is used when a monitor doesn't list its own accepted
+// status codes
+var defaultAcceptedStatus = []string{"200-299"}
+
+func checkHTTP(m types.Monitor) (result types.CheckResult) {
+	req, err := newHTTPRequest(m)
+	if err != nil {
+		fail(&result, types.ErrorClassConfig, err.Error())
+		return
+	}
+
+	inspector, err := newTLSInspector(m, req.URL.Hostname())
+	if err != nil {
+		fail(&result, types.ErrorClassConfig, err.Error())
+		return
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: inspector.config(),
+		// A custom TLS config disables HTTP/2 unless asked for
+		ForceAttemptHTTP2: true,
+		// Each check gets its own transport, so don't leave connections
+		// open for it to reuse
+		DisableKeepAlives: true,
+	}
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{
+		Timeout:   monitorTimeout(m),
+		Transport: transport,
+	}
+	if m.NoFollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	timer := newPhaseTimer()
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timer.trace()))
+
+	var bodyRead time.Time
+	defer func() { timer.apply(&result, bodyRead) }()
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.ResponseTime = time.Since(start)
+	defer inspector.apply(m, &result)
+
+	if err != nil {
+		failWithError(&result, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.Protocol = resp.Proto
+
+	body, err := readBody(resp.Body, maxBodySize(m))
+	bodyRead = time.Now()
+	result.ResponseSize = max(int64(len(body)), resp.ContentLength)
+	if err != nil {
+		fail(&result, classifyError(err), fmt.Sprintf("reading body: %v", err))
+		return
+	}
+
+	if !statusAccepted(resp.StatusCode, m.AcceptedStatus) {
+		fail(&result, types.ErrorClassHTTPStatus, fmt.Sprintf("unexpected status %s", resp.Status))
+		return
+	}
+
+	failure, warning := evaluateAssertions(m.Assertions, body)
+	if failure != "" {
+		fail(&result, types.ErrorClassAssertion, failure)
+		return
+	}
+
+	result.IsUp = true
+	warn(&result, warning)
+	return
+}
+
+func newHTTPRequest(m types.Monitor) (*http.Request, error) {
+	method := m.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if m.Body != "" {
+		body = strings.NewReader(m.Body)
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(method), m.URL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range m.Headers {
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+
+	return req, nil
+}
+
+// statusAccepted reports whether code matches one of the accepted patterns.
+// Patterns are single codes ("401"), ranges ("200-299") or classes ("3xx").
+func statusAccepted(code int, accepted []string) bool {
+	if len(accepted) == 0 {
+		accepted = defaultAcceptedStatus
+	}
+
+	for _, pattern := range accepted {
+		low, high, err := parseStatusPattern(pattern)
+		if err == nil && code >= low && code <= high {
+			return true
+		}
+	}
+	return false
+}
+
+func parseStatusPattern(pattern string) (low, high int, err error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+	if class, ok := strings.CutSuffix(pattern, "xx"); ok {
+		n, err := strconv.Atoi(class)
+		if err != nil || n < 1 || n > 5 {
+			return 0, 0, fmt.Errorf("invalid status class %q", pattern)
+		}
+		return n * 100, n*100 + 99, nil
+	}
+
+	if from, to, ok := strings.Cut(pattern, "-"); ok {
+		low, err = strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid status range %q", pattern)
+		}
+		high, err = strconv.Atoi(strings.TrimSpace(to))
+		if err != nil || high < low {
+			return 0, 0, fmt.Errorf("invalid status range %q", pattern)
+		}
+		return low, high, nil
+	}
+
+	low, err = strconv.Atoi(pattern)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid status code %q", pattern)
+	}
+	return low, low, nil
+}
+
+func validateHTTPMonitor(m types.Monitor) error {
+	if _, err := newHTTPRequest(m); err != nil {
+		return err
+	}
+	for _, pattern := range m.AcceptedStatus {
+		if _, _, err := parseStatusPattern(pattern); err != nil {
+			return err
+		}
+	}
+	if m.TLSCABundle != "" {
+		if _, err := parseCABundle(m.TLSCABundle); err != nil {
+			return err
+		}
+	}
+	if m.CertWarnDays < 0 || m.CertDownDays < 0 {
+		return fmt.Errorf("certificate expiry thresholds must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// lengthSuffix can be appended to a path to compare the length of an array,
+// object or string instead of its value
+const lengthSuffix = ".length()"
+
+func evaluateJSONAssertion(a types.Assertion, body []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return fmt.Errorf("body is not valid JSON: %v", err)
+	}
+
+	path, wantLength := strings.CutSuffix(a.Path, lengthSuffix)
+	value, found, err := lookupJSONPath(doc, path)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return fmt.Errorf("%s does not exist", a.Path)
+	}
+	if a.Op == types.JSONOpExists {
+		return nil
+	}
+
+	if wantLength {
+		switch v := value.(type) {
+		case []interface{}:
+			value = json.Number(strconv.Itoa(len(v)))
+		case map[string]interface{}:
+			value = json.Number(strconv.Itoa(len(v)))
+		case string:
+			value = json.Number(strconv.Itoa(len(v)))
+		default:
+			return fmt.Errorf("%s has no length", path)
+		}
+	}
+
+	ok, err := compareJSONValue(value, a.Op, a.Value)
+	if err != nil {
+		return fmt.Errorf("%s: %v", a.Path, err)
+	}
+	if !ok {
+		return fmt.Errorf("%s is %s, expected %s %s", a.Path, formatJSONValue(value), a.Op, a.Value)
+	}
+	return nil
+}
+
+// lookupJSONPath walks a decoded document following a path such as
+// $.checks[0].status or $['content-type']
+func lookupJSONPath(doc interface{}, path string) (interface{}, bool, error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	current := doc
+	for _, segment := range segments {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[segment]
+			if !ok {
+				return nil, false, nil
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil {
+				return nil, false, nil
+			}
+			if index < 0 {
+				index += len(node)
+			}
+			if index < 0 || index >= len(node) {
+				return nil, false, nil
+			}
+			current = node[index]
+		default:
+			return nil, false, nil
+		}
+	}
+
+	return current, true, nil
+}
+
+func parseJSONPath(path string) ([]string, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(path), "$")
+	if !ok {
+		return nil, fmt.Errorf("invalid JSON path %q: must start with $", path)
+	}
+
+	var segments []string
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: empty key", path)
+			}
+			segments = append(segments, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: unclosed [", path)
+			}
+			key := strings.Trim(rest[1:end], `'"`)
+			segments = append(segments, key)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q: unexpected %q", path, rest[0])
+		}
+	}
+
+	return segments, nil
+}
+
+func compareJSONValue(value interface{}, op, want string) (bool, error) {
+	got := formatJSONValue(value)
+
+	switch op {
+	case types.JSONOpEquals, types.JSONOpNotEquals:
+		equal := got == want
+		if gotNum, ok := value.(json.Number); ok {
+			if g, err := gotNum.Float64(); err == nil {
+				if w, err := strconv.ParseFloat(want, 64); err == nil {
+					equal = g == w
+				}
+			}
+		}
+		return equal == (op == types.JSONOpEquals), nil
+	case types.JSONOpGreater, types.JSONOpGreaterEqual, types.JSONOpLess, types.JSONOpLessEqual:
+		number, ok := value.(json.Number)
+		if !ok {
+			return false, fmt.Errorf("%s is not a number", got)
+		}
+		g, err := number.Float64()
+		if err != nil {
+			return false, err
+		}
+		w, err := strconv.ParseFloat(want, 64)
+		if err != nil {
+			return false, fmt.Errorf("%q is not a number", want)
+		}
+		switch op {
+		case types.JSONOpGreater:
+			return g > w, nil
+		case types.JSONOpGreaterEqual:
+			return g >= w, nil
+		case types.JSONOpLess:
+			return g < w, nil
+		default:
+			return g <= w, nil
+		}
+	}
+
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+func formatJSONValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
+
+func validateJSONAssertion(a types.Assertion) error {
+	path, _ := strings.CutSuffix(a.Path, lengthSuffix)
+	if _, err := parseJSONPath(path); err != nil {
+		return err
+	}
+
+	switch a.Op {
+	case types.JSONOpExists, types.JSONOpEquals, types.JSONOpNotEquals:
+	case types.JSONOpGreater, types.JSONOpGreaterEqual, types.JSONOpLess, types.JSONOpLessEqual:
+		if _, err := strconv.ParseFloat(a.Value, 64); err != nil {
+			return fmt.Errorf("%s %s needs a numeric value, got %q", a.Path, a.Op, a.Value)
+		}
+	default:
+		return fmt.Errorf("unknown JSON assertion operator %q", a.Op)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // so timezones work where the system has no zoneinfo
+
+	"github.com/robfig/cron/v3"
+	"github.com/watzon/go-up/internal/database"
+	"github.com/watzon/go-up/internal/types"
+)
+
+// cronParser accepts standard five field cron expressions and descriptors
+// like @daily
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// window is a maintenance window with its schedule and timezone parsed
+type window struct {
+	types.MaintenanceWindow
+	schedule cron.Schedule
+	location *time.Location
+}
+
+func parseWindow(w types.MaintenanceWindow) (window, error) {
+	parsed := window{MaintenanceWindow: w, location: time.Local}
+	if w.Timezone != "" {
+		location, err := time.LoadLocation(w.Timezone)
+		if err != nil {
+			return parsed, fmt.Errorf("unknown timezone %q", w.Timezone)
+		}
+		parsed.location = location
+	}
+	if w.Schedule != "" {
+		schedule, err := parseSchedule(w.Schedule)
+		if err != nil {
+			return parsed, err
+		}
+		parsed.schedule = schedule
+	}
+	return parsed, nil
+}
+
+// parseSchedule parses a cron expression, or a weekly schedule of days and
+// a time like "sat,sun 02:00" which is turned into one
+func parseSchedule(spec string) (cron.Schedule, error) {
+	if fields := strings.Fields(spec); len(fields) == 2 && strings.Contains(fields[1], ":") {
+		at, err := time.Parse("15:04", fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q, expected HH:MM", fields[1])
+		}
+		spec = fmt.Sprintf("%d %d * * %s", at.Minute(), at.Hour(), fields[0])
+	}
+
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	return schedule, nil
+}
+
+// active reports whether the window covers t. A recurring window is active
+// when it last started less than its duration before t.
+func (w window) active(t time.Time) bool {
+	if w.schedule == nil {
+		return !t.Before(w.Start) && t.Before(w.End)
+	}
+	return !w.schedule.Next(t.In(w.location).Add(-w.Duration)).After(t)
+}
+
+// nextStart returns when the window next starts after t, or zero if it
+// never will
+func (w window) nextStart(t time.Time) time.Time {
+	if w.schedule == nil {
+		if t.Before(w.Start) {
+			return w.Start
+		}
+		return time.Time{}
+	}
+	return w.schedule.Next(t.In(w.location))
+}
+
+// covers reports whether the window applies to a monitor, by name or tag
+func (w window) covers(m types.Monitor) bool {
+	if slices.Contains(w.Monitors, m.Name) {
+		return true
+	}
+	for _, tag := range m.Tags {
+		if slices.Contains(w.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// maintenance keeps the parsed maintenance windows so checks can tell
+// whether a monitor is under maintenance without going to the database
+type maintenance struct {
+	db *database.DB
+
+	mu      sync.RWMutex
+	windows []window
+}
+
+func newMaintenance(db *database.DB) *maintenance {
+	return &maintenance{db: db}
+}
+
+// load reads the windows from the database
+func (mt *maintenance) load() error {
+	stored, err := mt.db.ListMaintenanceWindows()
+	if err != nil {
+		return err
+	}
+
+	windows := make([]window, 0, len(stored))
+	for _, w := range stored {
+		parsed, err := parseWindow(w)
+		if err != nil {
+			log.Printf("Skipping maintenance window %s: %v", w.Name, err)
+			continue
+		}
+		windows = append(windows, parsed)
+	}
+
+	mt.mu.Lock()
+	mt.windows = windows
+	mt.mu.Unlock()
+	return nil
+}
+
+// active returns the window a monitor is under maintenance in at now. When
+// windows overlap, one that pauses checks wins.
+func (mt *maintenance) active(m types.Monitor, now time.Time) (types.MaintenanceWindow, bool) {
+	mt.mu.RLock()
+	defer mt.mu.RUnlock()
+
+	var found *window
+	for i := range mt.windows {
+		w := &mt.windows[i]
+		if !w.covers(m) || !w.active(now) {
+			continue
+		}
+		if found == nil || (w.Mode == types.MaintenancePause && found.Mode != types.MaintenancePause) {
+			found = w
+		}
+	}
+	if found == nil {
+		return types.MaintenanceWindow{}, false
+	}
+	return found.MaintenanceWindow, true
+}
+
+// list returns the windows with whether they're active at now and when
+// they next start
+func (mt *maintenance) list(now time.Time) []types.MaintenanceWindow {
+	mt.mu.RLock()
+	defer mt.mu.RUnlock()
+
+	windows := make([]types.MaintenanceWindow, len(mt.windows))
+	for i, w := range mt.windows {
+		windows[i] = w.MaintenanceWindow
+		windows[i].Active = w.active(now)
+		windows[i].NextStart = w.nextStart(now)
+	}
+	return windows
+}
+
+// validateMaintenance checks a new maintenance window, defaulting its mode
+// and working out a one-off window's start and end
+func (s *Service) validateMaintenance(w *types.MaintenanceWindow, now time.Time) error {
+	if w.Name == "" {
+		return fmt.Errorf("maintenance window needs a name")
+	}
+	if len(w.Monitors) == 0 && len(w.Tags) == 0 {
+		return fmt.Errorf("maintenance window needs monitors or tags")
+	}
+	windows, err := s.db.ListMaintenanceWindows()
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(windows, func(existing types.MaintenanceWindow) bool { return existing.Name == w.Name }) {
+		return fmt.Errorf("maintenance window %s already exists", w.Name)
+	}
+	monitors, err := s.db.ListMonitors()
+	if err != nil {
+		return err
+	}
+	for _, name := range w.Monitors {
+		if !slices.ContainsFunc(monitors, func(m types.Monitor) bool { return m.Name == name }) {
+			return fmt.Errorf("monitor %s not found", name)
+		}
+	}
+
+	if w.Mode == "" {
+		w.Mode = types.MaintenancePause
+	}
+	if w.Mode != types.MaintenancePause && w.Mode != types.MaintenanceSilence {
+		return fmt.Errorf("unknown mode %q, expected pause or silence", w.Mode)
+	}
+	if w.Duration < 0 {
+		return fmt.Errorf("duration must not be negative, got %s", w.Duration)
+	}
+
+	if w.Schedule != "" {
+		if !w.Start.IsZero() || !w.End.IsZero() {
+			return fmt.Errorf("recurring windows can't have a start or end")
+		}
+		if w.Duration == 0 {
+			return fmt.Errorf("recurring windows need a duration")
+		}
+	} else {
+		if w.Start.IsZero() {
+			w.Start = now
+		}
+		if w.End.IsZero() {
+			if w.Duration == 0 {
+				return fmt.Errorf("one-off windows need an end or a duration")
+			}
+			w.End = w.Start.Add(w.Duration)
+		}
+		if !w.End.After(w.Start) {
+			return fmt.Errorf("window must end after it starts")
+		}
+		if !w.End.After(now) {
+			return fmt.Errorf("window has already ended")
+		}
+		w.Duration = w.End.Sub(w.Start)
+	}
+
+	_, err = parseWindow(*w)
+	return err
+}
@@ -0,0 +1,187 @@
+package daemon
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/watzon/go-up/internal/database"
+	"github.com/watzon/go-up/internal/notify"
+	"github.com/watzon/go-up/internal/types"
+)
+
+const (
+	outboxTick      = time.Second
+	outboxBatchSize = 64
+	// Failed deliveries are retried after outboxBaseDelay, doubling each
+	// time up to outboxMaxDelay, until outboxMaxAttempts have been made
+	outboxBaseDelay   = 10 * time.Second
+	outboxMaxDelay    = 30 * time.Minute
+	outboxMaxAttempts = 10
+)
+
+// queuedEvent is how an event is stored in the outbox. The fields left out
+// of the event's JSON are stored alongside it.
+type queuedEvent struct {
+	Event            notify.Event
+	Recent           []types.HistoricalStat
+	RoutingKeys      map[string]string
+	IncidentDuration time.Duration
+	MonitorConfig    types.Monitor
+	ServiceStatus    types.ServiceStatus
+}
+
+// outbox delivers notifications stored in the database, so an alert isn't
+// lost when a channel is unreachable or the daemon restarts before it is
+// sent. Failed deliveries are retried with exponential backoff and every
+// attempt is logged.
+type outbox struct {
+	db       *database.DB
+	notifier *notify.Dispatcher
+	wake     chan struct{}
+
+	mu       sync.Mutex
+	inFlight map[int]bool
+}
+
+func newOutbox(db *database.DB, notifier *notify.Dispatcher) *outbox {
+	return &outbox{
+		db:       db,
+		notifier: notifier,
+		wake:     make(chan struct{}, 1),
+		inFlight: make(map[int]bool),
+	}
+}
+
+// queue stores an event for delivery to each of the channels
+func (o *outbox) queue(channels []string, event notify.Event) error {
+	payload, err := json.Marshal(queuedEvent{
+		Event:            event,
+		Recent:           event.Recent,
+		RoutingKeys:      event.RoutingKeys,
+		IncidentDuration: event.IncidentDuration,
+		MonitorConfig:    event.MonitorConfig,
+		ServiceStatus:    event.ServiceStatus,
+	})
+	if err != nil {
+		return err
+	}
+	if err := o.db.QueueNotifications(event.MonitorID, channels, event.Type, event.Message, payload); err != nil {
+		return err
+	}
+	o.poke()
+	return nil
+}
+
+// poke wakes the outbox to dispatch without waiting for the next tick
+func (o *outbox) poke() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (o *outbox) run() {
+	ticker := time.NewTicker(outboxTick)
+	defer ticker.Stop()
+
+	for {
+		o.dispatch(time.Now())
+		select {
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// dispatch starts delivering each due notification that isn't already
+// being delivered. Only the oldest pending notification for a monitor and
+// channel is due, so they are delivered one at a time in order.
+func (o *outbox) dispatch(now time.Time) {
+	due, err := o.db.DueNotifications(now, outboxBatchSize)
+	if err != nil {
+		log.Printf("Error listing due notifications: %v", err)
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, n := range due {
+		if o.inFlight[n.ID] {
+			continue
+		}
+		o.inFlight[n.ID] = true
+		go o.deliver(n)
+	}
+}
+
+func (o *outbox) deliver(n types.Notification) {
+	defer func() {
+		o.mu.Lock()
+		delete(o.inFlight, n.ID)
+		o.mu.Unlock()
+	}()
+
+	attempt := types.DeliveryAttempt{Attempt: n.Attempts + 1}
+	status := types.NotificationDelivered
+
+	var queued queuedEvent
+	err := json.Unmarshal(n.Payload, &queued)
+	event := queued.Event
+	event.Recent = queued.Recent
+	event.RoutingKeys = queued.RoutingKeys
+	event.IncidentDuration = queued.IncidentDuration
+	event.MonitorConfig = queued.MonitorConfig
+	event.ServiceStatus = queued.ServiceStatus
+
+	// Channels are only loaded at startup, so there's no point retrying one
+	// that isn't configured
+	switch {
+	case err != nil:
+		status = types.NotificationFailed
+	case !o.notifier.Has(n.Channel):
+		status = types.NotificationFailed
+		attempt.Error = "unknown channel " + n.Channel
+	default:
+		start := time.Now()
+		attempt.StatusCode, err = o.notifier.Deliver(n.Channel, event)
+		attempt.Duration = time.Since(start)
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	next := time.Now()
+	switch {
+	case status == types.NotificationFailed:
+		log.Printf("Error sending %s notification for %s to %s: %s", n.Event, event.Monitor, n.Channel, attempt.Error)
+	case err == nil:
+		log.Printf("Sent %s notification for %s to %s", n.Event, event.Monitor, n.Channel)
+	case attempt.Attempt >= outboxMaxAttempts:
+		status = types.NotificationFailed
+		log.Printf("Error sending %s notification for %s to %s, giving up after %d attempts: %v",
+			n.Event, event.Monitor, n.Channel, attempt.Attempt, err)
+	default:
+		status = types.NotificationPending
+		delay := retryDelay(attempt.Attempt)
+		next = next.Add(delay)
+		log.Printf("Error sending %s notification for %s to %s, retrying in %s: %v",
+			n.Event, event.Monitor, n.Channel, delay, err)
+	}
+
+	if err := o.db.RecordDelivery(n.ID, attempt, status, next); err != nil {
+		log.Printf("Error recording delivery of notification %d: %v", n.ID, err)
+	}
+	// The next notification for the monitor and channel may be waiting
+	o.poke()
+}
+
+// retryDelay is the backoff before retrying after the given attempt
+func retryDelay(attempt int) time.Duration {
+	delay := outboxBaseDelay
+	for i := 1; i < attempt && delay < outboxMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxDelay)
+}
@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// Events a job can report to its push URL. A plain ping is treated as finish.
+const (
+	pushStart  = "start"
+	pushFinish = "finish"
+	pushFail   = "fail"
+)
+
+// pushCheckInterval caps how long a missed heartbeat can go unnoticed for
+// push monitors with long periods
+const pushCheckInterval = 30 * time.Second
+
+// maxPushMessageSize caps how much of a fail ping's body is kept as the error
+const maxPushMessageSize = 1024
+
+// newPushToken returns a random token identifying a push monitor's URL
+func newPushToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func validatePushMonitor(m types.Monitor) error {
+	if m.PushGrace < 0 {
+		return fmt.Errorf("grace period must not be negative, got %s", m.PushGrace)
+	}
+	return nil
+}
+
+// pushHandler serves the URLs jobs ping to report heartbeats:
+//
+//	/push/<token>         heartbeat, same as finish
+//	/push/<token>/start   the job started, used to time the run
+//	/push/<token>/finish  the job finished successfully
+//	/push/<token>/fail    the job failed, ?msg= or the body is kept as the error
+func (s *Service) pushHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/push/{token}", s.handlePush)
+	mux.HandleFunc("/push/{token}/{event}", s.handlePush)
+	return mux
+}
+
+func (s *Service) handlePush(w http.ResponseWriter, r *http.Request) {
+	m, err := s.db.GetMonitorByPushToken(r.PathValue("token"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	event := r.PathValue("event")
+	switch event {
+	case "", pushStart, pushFinish, pushFail:
+	default:
+		http.Error(w, fmt.Sprintf("unknown event %q", event), http.StatusBadRequest)
+		return
+	}
+
+	if !m.IsActive {
+		fmt.Fprintln(w, "paused")
+		return
+	}
+
+	// Start times are stored so a run can be timed across a restart
+	now := time.Now()
+	if event == pushStart {
+		if err := s.db.SetPushStarted(m.ID, &now); err != nil {
+			log.Printf("Error recording start of %s: %v", m.Name, err)
+			http.Error(w, "failed to record start", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintln(w, "OK")
+		return
+	}
+
+	result := types.CheckResult{IsUp: true}
+	if event == pushFail {
+		message := r.URL.Query().Get("msg")
+		if message == "" {
+			body, _ := io.ReadAll(io.LimitReader(r.Body, maxPushMessageSize+1))
+			message = truncate(strings.TrimSpace(string(body)), maxPushMessageSize)
+		}
+		if message == "" {
+			message = "job reported failure"
+		}
+		fail(&result, types.ErrorClassJobFailed, message)
+	}
+
+	started, err := s.db.PushStarted(m.ID)
+	if err != nil {
+		log.Printf("Error getting start of %s: %v", m.Name, err)
+	} else if !started.IsZero() {
+		result.ResponseTime = now.Sub(started)
+		if err := s.db.SetPushStarted(m.ID, nil); err != nil {
+			log.Printf("Error clearing start of %s: %v", m.Name, err)
+		}
+	}
+
+	setStatus(m, &result)
+	if err := s.record(m, result); err != nil {
+		log.Printf("Error adding stats for %s: %v", m.Name, err)
+		http.Error(w, "failed to record heartbeat", http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintln(w, "OK")
+}
+
+// checkHeartbeat records a push monitor as down when no heartbeat has
+// arrived within its interval plus grace period. Only one missed heartbeat
+// is recorded per interval so uptime isn't skewed by how often this runs.
+func (s *Service) checkHeartbeat(m types.Monitor) time.Duration {
+	last, err := s.db.LastHeartbeat(m.ID)
+	if err != nil {
+		log.Printf("Error getting last heartbeat for %s: %v", m.Name, err)
+		return 0
+	}
+
+	now := time.Now()
+	interval := monitorInterval(m)
+	deadline := last.Add(interval + m.PushGrace)
+	if now.Before(deadline) {
+		return 0
+	}
+
+	lastCheck, err := s.db.LastCheck(m.ID)
+	if err != nil {
+		log.Printf("Error getting last check for %s: %v", m.Name, err)
+		return 0
+	}
+	if lastCheck.After(deadline) && now.Sub(lastCheck) < interval {
+		return 0
+	}
+
+	var result types.CheckResult
+	fail(&result, types.ErrorClassMissedHeartbeat,
+		fmt.Sprintf("no heartbeat received for %s", now.Sub(last).Round(time.Second)))
+	setStatus(m, &result)
+	if err := s.record(m, result); err != nil {
+		log.Printf("Error adding stats for %s: %v", m.Name, err)
+	}
+	return 0
+}
@@ -0,0 +1,234 @@
+package daemon
+
+import (
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/watzon/go-up/internal/database"
+	"github.com/watzon/go-up/internal/types"
+)
+
+const (
+	defaultWorkers = 16
+	schedulerTick  = 250 * time.Millisecond
+	reloadInterval = 10 * time.Second
+	maxQueueSize   = 4096
+	// lagSmoothing is the weight given to the newest sample in the average lag
+	lagSmoothing = 0.1
+)
+
+type job struct {
+	monitor types.Monitor
+	due     time.Time
+}
+
+// scheduler runs checks on a bounded pool of workers. Each monitor gets a
+// random phase within its interval so checks are spread out instead of all
+// firing at once, and a monitor is never checked twice concurrently.
+type scheduler struct {
+	db      *database.DB
+	check   func(types.Monitor) time.Duration
+	workers int
+	jobs    chan job
+
+	mu        sync.Mutex
+	monitors  []types.Monitor
+	nextRun   map[int]time.Time
+	inFlight  map[int]bool
+	completed uint64
+	skipped   uint64
+	lastLag   time.Duration
+	avgLag    time.Duration
+	maxLag    time.Duration
+}
+
+// newScheduler creates a scheduler that runs check for each due monitor. When
+// check returns a positive delay the monitor is run again after that delay
+// instead of waiting for its next interval.
+func newScheduler(db *database.DB, workers int, check func(types.Monitor) time.Duration) *scheduler {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	return &scheduler{
+		db:       db,
+		check:    check,
+		workers:  workers,
+		jobs:     make(chan job, maxQueueSize),
+		nextRun:  make(map[int]time.Time),
+		inFlight: make(map[int]bool),
+	}
+}
+
+func (s *scheduler) run() {
+	for i := 0; i < s.workers; i++ {
+		go s.work()
+	}
+
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	var lastReload time.Time
+	for now := range ticker.C {
+		if now.Sub(lastReload) >= reloadInterval {
+			if err := s.reload(now); err != nil {
+				log.Printf("Error listing monitors: %v", err)
+			} else {
+				lastReload = now
+			}
+		}
+		s.dispatch(now)
+	}
+}
+
+// reload refreshes the list of active monitors, giving new ones a random
+// first run within their interval and forgetting removed or paused ones
+func (s *scheduler) reload(now time.Time) error {
+	monitors, err := s.db.ListMonitors()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active := make([]types.Monitor, 0, len(monitors))
+	seen := make(map[int]bool, len(monitors))
+	for _, m := range monitors {
+		if !m.IsActive {
+			continue
+		}
+		active = append(active, m)
+		seen[m.ID] = true
+
+		if _, scheduled := s.nextRun[m.ID]; !scheduled {
+			s.nextRun[m.ID] = now.Add(jitter(checkInterval(m)))
+		}
+	}
+
+	for id := range s.nextRun {
+		if !seen[id] {
+			delete(s.nextRun, id)
+		}
+	}
+	s.monitors = active
+
+	return nil
+}
+
+func (s *scheduler) dispatch(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, m := range s.monitors {
+		due := s.nextRun[m.ID]
+		if now.Before(due) {
+			continue
+		}
+
+		// Keep the monitor's phase unless it has fallen a whole interval behind
+		interval := checkInterval(m)
+		next := due.Add(interval)
+		if !next.After(now) {
+			next = now.Add(interval)
+		}
+		s.nextRun[m.ID] = next
+
+		if s.inFlight[m.ID] {
+			s.skipped++
+			log.Printf("Skipping check for %s: previous check still in flight", m.Name)
+			continue
+		}
+
+		select {
+		case s.jobs <- job{monitor: m, due: due}:
+			s.inFlight[m.ID] = true
+		default:
+			s.skipped++
+			log.Printf("Skipping check for %s: queue is full", m.Name)
+		}
+	}
+}
+
+func (s *scheduler) work() {
+	for j := range s.jobs {
+		s.recordLag(time.Since(j.due))
+		retryIn := s.check(j.monitor)
+
+		s.mu.Lock()
+		delete(s.inFlight, j.monitor.ID)
+		s.completed++
+		s.mu.Unlock()
+
+		if retryIn > 0 {
+			s.schedule(j.monitor.ID, time.Now().Add(retryIn))
+		}
+	}
+}
+
+// add starts scheduling a new monitor right away instead of waiting for the
+// next reload, with its first run at the given time
+func (s *scheduler) add(m types.Monitor, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.monitors {
+		if existing.ID == m.ID {
+			return
+		}
+	}
+	s.monitors = append(s.monitors, m)
+	s.nextRun[m.ID] = at
+}
+
+// schedule moves a monitor's next run forward to at, if that is sooner
+func (s *scheduler) schedule(id int, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if next, ok := s.nextRun[id]; !ok || at.Before(next) {
+		s.nextRun[id] = at
+	}
+}
+
+func (s *scheduler) recordLag(lag time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastLag = lag
+	if s.avgLag == 0 {
+		s.avgLag = lag
+	} else {
+		s.avgLag = time.Duration(lagSmoothing*float64(lag) + (1-lagSmoothing)*float64(s.avgLag))
+	}
+	if lag > s.maxLag {
+		s.maxLag = lag
+	}
+}
+
+func (s *scheduler) stats() types.SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued := len(s.jobs)
+	return types.SchedulerStats{
+		Workers:    s.workers,
+		Monitors:   len(s.monitors),
+		QueueDepth: queued,
+		Running:    len(s.inFlight) - queued,
+		Completed:  s.completed,
+		Skipped:    s.skipped,
+		LastLag:    s.lastLag,
+		AvgLag:     s.avgLag,
+		MaxLag:     s.maxLag,
+	}
+}
+
+func jitter(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	return rand.N(interval)
+}
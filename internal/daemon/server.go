@@ -4,12 +4,34 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"net/rpc"
 
 	"github.com/watzon/go-up/internal/database"
+	"github.com/watzon/go-up/internal/notify"
 )
 
-func Start(host string, port int) {
+// Config holds the daemon's settings
+type Config struct {
+	Host    string
+	Port    int
+	Workers int
+
+	// PushPort is the port the HTTP endpoint for push monitors listens on,
+	// 0 disables it. PushURL is the base URL jobs use to reach it.
+	PushPort int
+	PushURL  string
+
+	// AllowExec lets exec monitors run commands on the daemon's host. It is
+	// off by default since anyone who can reach the RPC port can add one.
+	AllowExec bool
+
+	// Channels are the notification channels monitors can alert, by name
+	Channels map[string]notify.ChannelConfig
+}
+
+func Start(cfg Config) {
+	host, port := cfg.Host, cfg.Port
 	log.Printf("Starting daemon on %s:%d...", host, port)
 	db, err := database.NewDB("go-up.db")
 	if err != nil {
@@ -22,7 +44,18 @@ func Start(host string, port int) {
 	}
 
 	log.Println("Creating new service...")
-	service := NewService(db)
+	if cfg.PushPort == 0 {
+		cfg.PushURL = ""
+	} else if cfg.PushURL == "" {
+		cfg.PushURL = fmt.Sprintf("http://%s:%d", host, cfg.PushPort)
+	}
+	notifier, err := notify.NewDispatcher(cfg.Channels)
+	if err != nil {
+		log.Fatalf("Error configuring notifications: %v", err)
+	}
+	log.Printf("Loaded %d notification channels", len(cfg.Channels))
+
+	service := NewService(db, cfg, notifier)
 	err = rpc.Register(service)
 	if err != nil {
 		log.Fatalf("Error registering RPC service: %v", err)
@@ -38,8 +71,18 @@ func Start(host string, port int) {
 
 	log.Printf("Daemon running and listening on %s:%d...", host, port)
 
-	go service.
-		periodicUpdate()
+	go service.scheduler.run()
+	go service.outbox.run()
+
+	if cfg.PushPort != 0 {
+		go func() {
+			addr := fmt.Sprintf("%s:%d", host, cfg.PushPort)
+			log.Printf("Push endpoint listening on %s...", addr)
+			if err := http.ListenAndServe(addr, service.pushHandler()); err != nil {
+				log.Fatalf("Error starting push endpoint: %v", err)
+			}
+		}()
+	}
 
 	for {
 		conn, err := listener.Accept()
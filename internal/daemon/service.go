@@ -1,22 +1,49 @@
 package daemon
 
 import (
-	"crypto/tls"
 	"fmt"
 	"log"
-	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/watzon/go-up/internal/database"
+	"github.com/watzon/go-up/internal/notify"
 	"github.com/watzon/go-up/internal/types"
 )
 
 type Service struct {
-	db *database.DB
+	db          *database.DB
+	scheduler   *scheduler
+	notifier    *notify.Dispatcher
+	outbox      *outbox
+	maintenance *maintenance
+
+	// pushURL is the base URL push monitors' heartbeat URLs are built from
+	pushURL string
+	// allowExec is whether exec monitors may run their commands
+	allowExec bool
+
+	mu       sync.Mutex
+	failures map[int]int
 }
 
-func NewService(db *database.DB) *Service {
-	return &Service{db: db}
+func NewService(db *database.DB, cfg Config, notifier *notify.Dispatcher) *Service {
+	s := &Service{
+		db:        db,
+		notifier:  notifier,
+		pushURL:   strings.TrimSuffix(cfg.PushURL, "/"),
+		allowExec: cfg.AllowExec,
+		failures:  make(map[int]int),
+	}
+	s.scheduler = newScheduler(db, cfg.Workers, s.runCheck)
+	s.outbox = newOutbox(db, notifier)
+	s.maintenance = newMaintenance(db)
+	if err := s.maintenance.load(); err != nil {
+		log.Printf("Error loading maintenance windows: %v", err)
+	}
+	return s
 }
 
 func (s *Service) ListMonitors(_ struct{}, reply *[]types.Monitor) error {
@@ -28,18 +55,46 @@ func (s *Service) ListMonitors(_ struct{}, reply *[]types.Monitor) error {
 	return nil
 }
 
-func (s *Service) AddMonitor(args struct{ Name, URL string }, reply *string) error {
-	err := s.db.AddMonitor(args.Name, args.URL)
-	if err != nil {
+func (s *Service) AddMonitor(args types.Monitor, reply *string) error {
+	if args.Type == "" {
+		args.Type = types.MonitorTypeHTTP
+	}
+	if args.Type == types.MonitorTypeDNS {
+		// Monitors are unique by record type, so "a" and "A" are the same
+		args.DNSRecordType = dnsRecordType(args)
+	}
+	if args.Type == types.MonitorTypePush {
+		if s.pushURL == "" {
+			err := fmt.Errorf("push monitors need the daemon's push endpoint to be enabled")
+			*reply = fmt.Sprintf("Failed to add monitor %s: %v", args.Name, err)
+			return err
+		}
+		token, err := newPushToken()
+		if err != nil {
+			*reply = fmt.Sprintf("Failed to add monitor %s: %v", args.Name, err)
+			return err
+		}
+		args.PushToken = token
+		args.URL = fmt.Sprintf("%s/push/%s", s.pushURL, token)
+	}
+	if err := validateMonitor(args, s.allowExec); err != nil {
+		*reply = fmt.Sprintf("Failed to add monitor %s for %s: %v", args.Name, args.URL, err)
+		return err
+	}
+	if err := s.validateAlerts(args); err != nil {
 		*reply = fmt.Sprintf("Failed to add monitor %s for %s: %v", args.Name, args.URL, err)
 		return err
 	}
 
-	responseTime, isUp, certExpiry := checkService(args.URL)
-	if err := s.db.AddStats(args.Name, int(responseTime.Milliseconds()), isUp, certExpiry); err != nil {
-		log.Printf("Warning: Failed to fetch initial stats for %s: %v", args.Name, err)
+	monitor, err := s.db.AddMonitor(args)
+	if err != nil {
+		*reply = fmt.Sprintf("Failed to add monitor %s for %s: %v", args.Name, args.URL, err)
+		return err
 	}
 
+	// Let a worker run the first check so it can't overlap a scheduled one
+	s.scheduler.add(monitor, time.Now())
+
 	*reply = fmt.Sprintf("Monitor '%s' added for %s", args.Name, args.URL)
 	return nil
 }
@@ -74,20 +129,69 @@ func (s *Service) ResumeMonitor(name string, reply *string) error {
 	return nil
 }
 
+func (s *Service) SetMonitorAlerts(args struct {
+	Name             string
+	Channels         []string
+	Events           []string
+	Severity         string
+	RoutingKeys      map[string]string
+	Delay            time.Duration
+	Repeat           time.Duration
+	EscalateAfter    time.Duration
+	EscalateChannels []string
+}, reply *string) error {
+	m := types.Monitor{
+		Name:             args.Name,
+		AlertChannels:    args.Channels,
+		AlertEvents:      args.Events,
+		AlertSeverity:    args.Severity,
+		AlertRoutingKeys: args.RoutingKeys,
+		AlertDelay:       args.Delay,
+		AlertRepeat:      args.Repeat,
+		EscalateAfter:    args.EscalateAfter,
+		EscalateChannels: args.EscalateChannels,
+	}
+	if err := s.validateAlerts(m); err != nil {
+		*reply = fmt.Sprintf("Failed to set alerts for monitor %s: %v", args.Name, err)
+		return err
+	}
+	if err := s.db.SetMonitorAlerts(m); err != nil {
+		*reply = fmt.Sprintf("Failed to set alerts for monitor %s: %v", args.Name, err)
+		return err
+	}
+	*reply = fmt.Sprintf("Alerts for monitor %s updated", args.Name)
+	return nil
+}
+
 func (s *Service) GetServiceStatus(name string, reply *types.ServiceStatus) error {
 	status, err := s.db.GetStats(name, 24*time.Hour)
 	if err != nil {
 		return err
 	}
+	monitors, err := s.db.ListMonitors()
+	if err != nil {
+		return err
+	}
+	if i := slices.IndexFunc(monitors, func(m types.Monitor) bool { return m.Name == name }); i >= 0 {
+		if w, ok := s.maintenance.active(monitors[i], time.Now()); ok {
+			status.Maintenance = w.Name
+		}
+	}
 	*reply = status
 	return nil
 }
 
+func (s *Service) GetSchedulerStats(_ struct{}, reply *types.SchedulerStats) error {
+	*reply = s.scheduler.stats()
+	return nil
+}
+
 func (s *Service) GetHistoricalStats(args struct {
-	MonitorID int
-	Count     int
+	MonitorID      int
+	Count          int
+	IncludeRetries bool
 }, reply *[]types.HistoricalStat) error {
-	stats, err := s.db.GetHistoricalStats(args.MonitorID, args.Count)
+	stats, err := s.db.GetHistoricalStats(args.MonitorID, args.Count, args.IncludeRetries)
 	if err != nil {
 		return err
 	}
@@ -95,57 +199,225 @@ func (s *Service) GetHistoricalStats(args struct {
 	return nil
 }
 
-func (s *Service) periodicUpdate() {
-	ticker := time.NewTicker(60 * time.Second)
-	defer ticker.Stop()
+func (s *Service) ListIncidents(args struct {
+	MonitorName string
+	Count       int
+	OpenOnly    bool
+}, reply *[]types.Incident) error {
+	incidents, err := s.db.ListIncidents(args.MonitorName, args.Count, args.OpenOnly)
+	if err != nil {
+		return err
+	}
+	*reply = incidents
+	return nil
+}
 
-	for range ticker.C {
-		monitors, err := s.db.ListMonitors()
-		if err != nil {
-			log.Printf("Error listing monitors: %v", err)
-			continue
-		}
+func (s *Service) GetIncident(id int, reply *types.Incident) error {
+	incident, err := s.db.GetIncident(id)
+	if err != nil {
+		return err
+	}
+	*reply = incident
+	return nil
+}
 
-		for _, monitor := range monitors {
-			if !monitor.IsActive {
-				continue
-			}
-
-			go func(m types.Monitor) {
-				responseTime, isUp, certExpiry := checkService(m.URL)
-				err := s.db.AddStats(m.Name, int(responseTime.Milliseconds()), isUp, certExpiry)
-				if err != nil {
-					log.Printf("Error adding stats for %s: %v", m.Name, err)
-				}
-			}(monitor)
-		}
+func (s *Service) AcknowledgeAlert(args struct {
+	MonitorName string
+	By          string
+}, reply *string) error {
+	incident, err := s.db.AcknowledgeIncident(args.MonitorName, args.By)
+	if err != nil {
+		*reply = fmt.Sprintf("Failed to acknowledge %s: %v", args.MonitorName, err)
+		return err
 	}
+	log.Printf("Incident #%d for %s acknowledged by %s", incident.ID, args.MonitorName, args.By)
+	*reply = fmt.Sprintf("Incident #%d for %s acknowledged", incident.ID, args.MonitorName)
+	return nil
 }
 
-func checkService(url string) (responseTime time.Duration, isUp bool, certExpiry time.Time) {
-	start := time.Now()
+func (s *Service) ListNotifications(args struct {
+	MonitorName string
+	Channel     string
+	Count       int
+}, reply *[]types.Notification) error {
+	notifications, err := s.db.ListNotifications(args.MonitorName, args.Channel, args.Count)
+	if err != nil {
+		return err
+	}
+	*reply = notifications
+	return nil
+}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+func (s *Service) ListAlerts(args struct {
+	MonitorName string
+	Count       int
+}, reply *[]types.Alert) error {
+	alerts, err := s.db.ListAlerts(args.MonitorName, args.Count)
+	if err != nil {
+		return err
 	}
+	*reply = alerts
+	return nil
+}
 
-	resp, err := client.Get(url)
-	responseTime = time.Since(start)
+func (s *Service) SetMonitorTags(args struct {
+	Name string
+	Tags []string
+}, reply *string) error {
+	if err := s.db.SetMonitorTags(args.Name, args.Tags); err != nil {
+		*reply = fmt.Sprintf("Failed to set tags for monitor %s: %v", args.Name, err)
+		return err
+	}
+	*reply = fmt.Sprintf("Tags for monitor %s updated", args.Name)
+	return nil
+}
+
+func (s *Service) AddMaintenanceWindow(args types.MaintenanceWindow, reply *string) error {
+	if err := s.validateMaintenance(&args, time.Now()); err != nil {
+		*reply = fmt.Sprintf("Failed to add maintenance window %s: %v", args.Name, err)
+		return err
+	}
+	if _, err := s.db.AddMaintenanceWindow(args); err != nil {
+		*reply = fmt.Sprintf("Failed to add maintenance window %s: %v", args.Name, err)
+		return err
+	}
+	if err := s.maintenance.load(); err != nil {
+		return err
+	}
+	log.Printf("Maintenance window %s added", args.Name)
+	*reply = fmt.Sprintf("Maintenance window %s added", args.Name)
+	return nil
+}
+
+func (s *Service) ListMaintenanceWindows(_ struct{}, reply *[]types.MaintenanceWindow) error {
+	*reply = s.maintenance.list(time.Now())
+	return nil
+}
+
+func (s *Service) RemoveMaintenanceWindow(name string, reply *string) error {
+	if err := s.db.RemoveMaintenanceWindow(name); err != nil {
+		*reply = fmt.Sprintf("Failed to remove maintenance window %s: %v", name, err)
+		return err
+	}
+	if err := s.maintenance.load(); err != nil {
+		return err
+	}
+	log.Printf("Maintenance window %s removed", name)
+	*reply = fmt.Sprintf("Maintenance window %s removed", name)
+	return nil
+}
 
+// TestNotification sends a test event for a monitor straight to a channel,
+// bypassing the outbox so the result can be reported back
+func (s *Service) TestNotification(args struct {
+	Channel     string
+	MonitorName string
+}, reply *string) error {
+	if !s.notifier.Has(args.Channel) {
+		*reply = fmt.Sprintf("Unknown notification channel %s", args.Channel)
+		return fmt.Errorf("unknown notification channel %s", args.Channel)
+	}
+
+	monitors, err := s.db.ListMonitors()
 	if err != nil {
-		isUp = false
-		return
+		return err
+	}
+	i := slices.IndexFunc(monitors, func(m types.Monitor) bool { return m.Name == args.MonitorName })
+	if i < 0 {
+		*reply = fmt.Sprintf("Monitor %s not found", args.MonitorName)
+		return fmt.Errorf("monitor %s not found", args.MonitorName)
 	}
-	defer resp.Body.Close()
 
-	isUp = resp.StatusCode >= 200 && resp.StatusCode < 300
+	event, err := s.testEvent(monitors[i])
+	if err != nil {
+		*reply = fmt.Sprintf("Failed to build test notification: %v", err)
+		return err
+	}
+	code, err := s.notifier.Deliver(args.Channel, event)
+	if err != nil {
+		*reply = fmt.Sprintf("Failed to send test notification to %s: %v", args.Channel, err)
+		return err
+	}
+	// Tests aren't sent through the outbox, and the alert one opens is
+	// resolved as its own step so a failed resolve doesn't resend it
+	if _, err := s.notifier.Resolve(args.Channel, event); err != nil {
+		*reply = fmt.Sprintf("Sent test notification to %s, but failed to resolve it: %v", args.Channel, err)
+		return err
+	}
+
+	log.Printf("Sent test notification for %s to %s", args.MonitorName, args.Channel)
+	*reply = fmt.Sprintf("Sent test notification for %s to %s", args.MonitorName, args.Channel)
+	if code != 0 {
+		*reply += fmt.Sprintf(" (HTTP %d)", code)
+	}
+	return nil
+}
+
+// runCheck checks a monitor and records the result. A failure is only
+// recorded as down once the monitor has failed more times in a row than it
+// has retries; until then each attempt is stored as a retry and the delay
+// before the next attempt is returned. Monitors are skipped while a
+// maintenance window pauses them.
+func (s *Service) runCheck(m types.Monitor) time.Duration {
+	if w, ok := s.maintenance.active(m, time.Now()); ok && w.Mode == types.MaintenancePause {
+		return 0
+	}
+	if m.Type == types.MonitorTypePush {
+		return s.checkHeartbeat(m)
+	}
+
+	var result types.CheckResult
+	if m.Type == types.MonitorTypeExec && !s.allowExec {
+		// Exec monitors added before exec was disabled don't run
+		fail(&result, types.ErrorClassConfig, errExecDisabled.Error())
+		setStatus(m, &result)
+	} else {
+		result = checkService(m)
+	}
 
-	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
-		certExpiry = resp.TLS.PeerCertificates[0].NotAfter
+	s.mu.Lock()
+	if result.IsUp {
+		delete(s.failures, m.ID)
+	} else {
+		s.failures[m.ID]++
+		result.Attempt = s.failures[m.ID]
+		result.IsRetry = result.Attempt <= m.Retries
 	}
+	s.mu.Unlock()
 
-	return
+	if err := s.record(m, result); err != nil {
+		log.Printf("Error adding stats for %s: %v", m.Name, err)
+	}
+
+	if result.IsRetry {
+		log.Printf("Check for %s failed (attempt %d of %d), retrying", m.Name, result.Attempt, m.Retries+1)
+		return monitorRetryInterval(m)
+	}
+	return 0
+}
+
+// record stores a check result, logs any change in the monitor's status
+// and sends the alerts it calls for
+func (s *Service) record(m types.Monitor, result types.CheckResult) error {
+	if _, ok := s.maintenance.active(m, time.Now()); ok {
+		result.Maintenance = true
+	}
+	change, err := s.db.AddStats(m.Name, result)
+	if err != nil {
+		return err
+	}
+	s.alert(m, result, change)
+
+	if change.From != "" && change.From != change.To {
+		log.Printf("Monitor %s changed from %s to %s", m.Name, change.From, change.To)
+	}
+	if incident := change.Incident; incident != nil {
+		switch {
+		case !incident.EndedAt.IsZero():
+			log.Printf("Incident #%d for %s resolved after %s", incident.ID, m.Name, incident.Duration.Round(time.Second))
+		case incident.CheckCount == 1:
+			log.Printf("Incident #%d opened for %s: %s", incident.ID, m.Name, incident.FirstError)
+		}
+	}
+	return nil
 }
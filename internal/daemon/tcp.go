@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// maxBannerSize caps how much of a TCP banner is read while looking for the
+// expected response
+const maxBannerSize = 4096
+
+var escapeReplacer = strings.NewReplacer(`\r`, "\r", `\n`, "\n", `\t`, "\t", `\\`, `\`)
+
+func checkTCP(m types.Monitor) (result types.CheckResult) {
+	start := time.Now()
+	timeout := monitorTimeout(m)
+
+	conn, err := net.DialTimeout("tcp", m.URL, timeout)
+	if err != nil {
+		result.ResponseTime = time.Since(start)
+		failWithError(&result, err)
+		return
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(start.Add(timeout)); err != nil {
+		result.ResponseTime = time.Since(start)
+		failWithError(&result, err)
+		return
+	}
+
+	if m.TCPSend != "" {
+		if _, err := conn.Write([]byte(escapeReplacer.Replace(m.TCPSend))); err != nil {
+			result.ResponseTime = time.Since(start)
+			failWithError(&result, err)
+			return
+		}
+	}
+
+	if m.TCPExpect != "" {
+		expect := []byte(escapeReplacer.Replace(m.TCPExpect))
+		banner := make([]byte, 0, maxBannerSize)
+		buf := make([]byte, 512)
+		var readErr error
+		for !bytes.Contains(banner, expect) && len(banner) < maxBannerSize {
+			n, err := conn.Read(buf)
+			banner = append(banner, buf[:n]...)
+			if err != nil {
+				readErr = err
+				break
+			}
+		}
+		result.ResponseTime = time.Since(start)
+		result.ResponseSize = int64(len(banner))
+
+		switch {
+		case bytes.Contains(banner, expect):
+			result.IsUp = true
+		case readErr != nil && len(banner) == 0:
+			failWithError(&result, readErr)
+		default:
+			fail(&result, types.ErrorClassUnexpected, fmt.Sprintf("response %q does not contain %q", truncate(string(banner), 64), m.TCPExpect))
+		}
+		return
+	}
+
+	result.ResponseTime = time.Since(start)
+	result.IsUp = true
+	return
+}
+
+func validateTCPAddress(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid TCP address %q, expected host:port: %w", addr, err)
+	}
+	if host == "" || port == "" {
+		return fmt.Errorf("invalid TCP address %q, expected host:port", addr)
+	}
+	return nil
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
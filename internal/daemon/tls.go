@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// tlsInspector verifies server certificates itself so that the certificate
+// details can be recorded even when verification fails
+type tlsInspector struct {
+	host       string
+	skipVerify bool
+	roots      *x509.CertPool
+
+	mu        sync.Mutex
+	state     *tls.ConnectionState
+	chain     []*x509.Certificate
+	verifyErr error
+}
+
+func newTLSInspector(m types.Monitor, host string) (*tlsInspector, error) {
+	inspector := &tlsInspector{
+		host:       host,
+		skipVerify: m.TLSSkipVerify,
+	}
+
+	if m.TLSCABundle != "" {
+		roots, err := parseCABundle(m.TLSCABundle)
+		if err != nil {
+			return nil, err
+		}
+		inspector.roots = roots
+	}
+
+	return inspector, nil
+}
+
+func (t *tlsInspector) config() *tls.Config {
+	return &tls.Config{
+		// Verification is done in verifyConnection instead
+		InsecureSkipVerify: true,
+		VerifyConnection:   t.verifyConnection,
+	}
+}
+
+func (t *tlsInspector) verifyConnection(cs tls.ConnectionState) error {
+	chain, err := t.verifyChain(cs)
+
+	t.mu.Lock()
+	// Only the first connection is recorded, later ones come from redirects
+	if t.state == nil {
+		t.state = &cs
+		t.chain = chain
+		t.verifyErr = err
+	}
+	t.mu.Unlock()
+
+	if t.skipVerify {
+		return nil
+	}
+	return err
+}
+
+func (t *tlsInspector) verifyChain(cs tls.ConnectionState) ([]*x509.Certificate, error) {
+	if len(cs.PeerCertificates) == 0 {
+		return nil, errors.New("server presented no certificates")
+	}
+
+	serverName := cs.ServerName
+	if serverName == "" {
+		serverName = t.host
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	chains, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         t.roots,
+		DNSName:       serverName,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return cs.PeerCertificates, err
+	}
+	return chains[0], nil
+}
+
+// apply records the certificate details of the inspected connection and
+// enforces the monitor's expiry thresholds
+func (t *tlsInspector) apply(m types.Monitor, result *types.CheckResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.state == nil || len(t.chain) == 0 {
+		return
+	}
+
+	leaf := t.chain[0]
+	result.CertIssuer = leaf.Issuer.String()
+	result.CertSANs = append([]string(nil), leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		result.CertSANs = append(result.CertSANs, ip.String())
+	}
+
+	for _, cert := range t.chain {
+		if result.CertExpiry.IsZero() || cert.NotAfter.Before(result.CertExpiry) {
+			result.CertExpiry = cert.NotAfter
+		}
+	}
+
+	if t.verifyErr != nil {
+		result.TLSError = t.verifyErr.Error()
+		if !t.skipVerify {
+			fail(result, types.ErrorClassTLS, fmt.Sprintf("certificate verification failed: %v", t.verifyErr))
+			return
+		}
+	}
+
+	remaining := time.Until(result.CertExpiry)
+	switch {
+	case m.CertDownDays > 0 && remaining < days(m.CertDownDays):
+		fail(result, types.ErrorClassCertificate, describeExpiry(result.CertExpiry))
+	case m.CertWarnDays > 0 && remaining < days(m.CertWarnDays):
+		warn(result, describeExpiry(result.CertExpiry))
+	}
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+func describeExpiry(expiry time.Time) string {
+	remaining := time.Until(expiry)
+	if remaining <= 0 {
+		return fmt.Sprintf("certificate expired on %s", expiry.Format("2006-01-02"))
+	}
+	return fmt.Sprintf("certificate expires in %d days (%s)", int(remaining.Hours()/24), expiry.Format("2006-01-02"))
+}
+
+// parseCABundle parses PEM encoded CA certificates. Monitors carry the
+// bundle itself rather than a path, so clients can't make the daemon read
+// files of their choosing.
+func parseCABundle(bundle string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(bundle)) {
+		return nil, fmt.Errorf("no certificates found in CA bundle")
+	}
+	return pool, nil
+}
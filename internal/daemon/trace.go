@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// phaseTimer collects how long each phase of an HTTP request took. Phases
+// are summed across redirects so they add up to the total request time.
+type phaseTimer struct {
+	mu sync.Mutex
+
+	dnsStart     time.Time
+	connectStart map[string]time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+
+	dns     time.Duration
+	connect time.Duration
+	tls     time.Duration
+	ttfb    time.Duration
+}
+
+func newPhaseTimer() *phaseTimer {
+	return &phaseTimer{connectStart: make(map[string]time.Time)}
+}
+
+func (p *phaseTimer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			p.mu.Lock()
+			p.dnsStart = time.Now()
+			p.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			p.mu.Lock()
+			p.dns += time.Since(p.dnsStart)
+			p.mu.Unlock()
+		},
+		ConnectStart: func(_, addr string) {
+			p.mu.Lock()
+			p.connectStart[addr] = time.Now()
+			p.mu.Unlock()
+		},
+		ConnectDone: func(_, addr string, err error) {
+			p.mu.Lock()
+			// Only count the dial that won, not ones raced in parallel
+			if start, ok := p.connectStart[addr]; ok && err == nil {
+				p.connect += time.Since(start)
+			}
+			delete(p.connectStart, addr)
+			p.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			p.mu.Lock()
+			p.tlsStart = time.Now()
+			p.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			p.mu.Lock()
+			p.tls += time.Since(p.tlsStart)
+			p.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			p.mu.Lock()
+			p.wroteRequest = time.Now()
+			p.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			p.mu.Lock()
+			p.firstByte = time.Now()
+			if !p.wroteRequest.IsZero() {
+				p.ttfb += p.firstByte.Sub(p.wroteRequest)
+			}
+			p.mu.Unlock()
+		},
+	}
+}
+
+// apply stores the phase timings on the result. bodyRead is when the
+// response body finished downloading, or zero if it wasn't read.
+func (p *phaseTimer) apply(result *types.CheckResult, bodyRead time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result.Timings = types.Timings{
+		DNS:     p.dns,
+		Connect: p.connect,
+		TLS:     p.tls,
+		TTFB:    p.ttfb,
+	}
+	if !p.firstByte.IsZero() && !bodyRead.IsZero() {
+		result.Timings.Transfer = bodyRead.Sub(p.firstByte)
+	}
+}
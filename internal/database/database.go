@@ -2,6 +2,8 @@ package database
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/watzon/go-up/internal/types"
@@ -9,11 +11,23 @@ import (
 	"gorm.io/gorm"
 )
 
+// stateActive is the state a monitor starts in before its first check
+const stateActive = "active"
+
+// maxIncidentChecks caps how many checks GetIncident returns
+const maxIncidentChecks = 100
+
 type DB struct {
 	*gorm.DB
 }
 
 func NewDB(dataSourceName string) (*DB, error) {
+	// WAL and a busy timeout let concurrent checks write without failing on
+	// a locked database
+	if !strings.Contains(dataSourceName, "?") {
+		dataSourceName += "?_journal_mode=WAL&_busy_timeout=5000"
+	}
+
 	db, err := gorm.Open(sqlite.Open(dataSourceName), &gorm.Config{})
 	if err != nil {
 		return nil, err
@@ -23,30 +37,57 @@ func NewDB(dataSourceName string) (*DB, error) {
 }
 
 func (db *DB) Init() error {
+	// Incidents opened before alert policies were alerted on straight away
+	backfillAlerts := db.Migrator().HasTable(&Incident{}) && !db.Migrator().HasColumn(&Incident{}, "AlertedAt")
+
 	// Auto migrate the schema
-	return db.AutoMigrate(&Monitor{}, &MonitorState{}, &Check{})
-}
+	if err := db.AutoMigrate(&Monitor{}, &MonitorState{}, &Check{}, &Incident{}, &Alert{}, &Notification{}, &DeliveryAttempt{},
+		&MaintenanceWindow{}); err != nil {
+		return err
+	}
 
-func (db *DB) AddMonitor(name, url string) error {
-	monitor := Monitor{
-		Name:     name,
-		URL:      url,
-		IsActive: true,
+	// Monitors used to be unique by URL alone, which stopped a hostname's
+	// DNS records being monitored separately
+	if db.Migrator().HasIndex(&Monitor{}, "idx_monitors_url") {
+		if err := db.Migrator().DropIndex(&Monitor{}, "idx_monitors_url"); err != nil {
+			return err
+		}
 	}
 
-	return db.Transaction(func(tx *gorm.DB) error {
+	if backfillAlerts {
+		if err := db.Model(&Incident{}).Where("alerted_at IS NULL").
+			Updates(map[string]any{"alerted_at": gorm.Expr("started_at"), "last_alert_at": gorm.Expr("started_at")}).Error; err != nil {
+			return err
+		}
+	}
+
+	// Checks recorded before statuses were stored are either up or down
+	return db.Model(&Check{}).Where("status = ''").
+		Update("status", gorm.Expr("CASE WHEN is_up THEN ? ELSE ? END", types.StatusUp, types.StatusDown)).Error
+}
+
+func (db *DB) AddMonitor(m types.Monitor) (types.Monitor, error) {
+	monitor := newMonitor(m)
+	monitor.IsActive = true
+
+	err := db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(&monitor).Error; err != nil {
 			return err
 		}
 
 		state := MonitorState{
 			MonitorID: monitor.ID,
-			State:     "active",
+			State:     stateActive,
 			StartedAt: time.Now(),
 		}
 
 		return tx.Create(&state).Error
 	})
+	if err != nil {
+		return types.Monitor{}, err
+	}
+
+	return monitor.toTypes(), nil
 }
 
 func (db *DB) RemoveMonitor(name string) error {
@@ -61,6 +102,35 @@ func (db *DB) ResumeMonitor(name string) error {
 	return db.Model(&Monitor{}).Where("name = ?", name).Update("is_active", true).Error
 }
 
+// SetMonitorAlerts replaces the alert settings of the monitor named m.Name
+func (db *DB) SetMonitorAlerts(m types.Monitor) error {
+	result := db.Model(&Monitor{}).Where("name = ?", m.Name).
+		Select("alert_channels", "alert_events", "alert_severity", "alert_routing_keys",
+			"alert_delay", "alert_repeat", "escalate_after", "escalate_channels").
+		Updates(newMonitor(m))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("monitor %s not found", m.Name)
+	}
+	return nil
+}
+
+// SetMonitorTags replaces the tags of a monitor
+func (db *DB) SetMonitorTags(name string, tags []string) error {
+	result := db.Model(&Monitor{}).Where("name = ?", name).
+		Select("tags").
+		Updates(Monitor{Tags: tags})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("monitor %s not found", name)
+	}
+	return nil
+}
+
 func (db *DB) ListMonitors() ([]types.Monitor, error) {
 	var dbMonitors []Monitor
 	if err := db.Find(&dbMonitors).Error; err != nil {
@@ -69,34 +139,556 @@ func (db *DB) ListMonitors() ([]types.Monitor, error) {
 
 	monitors := make([]types.Monitor, len(dbMonitors))
 	for i, m := range dbMonitors {
-		monitors[i] = types.Monitor{
-			ID:       int(m.ID),
-			Name:     m.Name,
-			URL:      m.URL,
-			IsActive: m.IsActive,
-		}
+		monitors[i] = m.toTypes()
 	}
 
 	return monitors, nil
 }
 
-func (db *DB) AddStats(monitorName string, responseTime int, isUp bool, certExpiry time.Time) error {
+func (db *DB) GetMonitorByPushToken(token string) (types.Monitor, error) {
+	var monitor Monitor
+	if err := db.Where("type = ? AND push_token = ?", types.MonitorTypePush, token).First(&monitor).Error; err != nil {
+		return types.Monitor{}, err
+	}
+	return monitor.toTypes(), nil
+}
+
+// PushStarted returns when a push monitor's running job started, or the
+// zero time if it isn't running
+func (db *DB) PushStarted(monitorID int) (time.Time, error) {
+	var monitor Monitor
+	if err := db.Select("push_started_at").First(&monitor, monitorID).Error; err != nil {
+		return time.Time{}, err
+	}
+	if monitor.PushStartedAt == nil {
+		return time.Time{}, nil
+	}
+	return *monitor.PushStartedAt, nil
+}
+
+// SetPushStarted records when a push monitor's job started, nil clears it
+func (db *DB) SetPushStarted(monitorID int, at *time.Time) error {
+	return db.Model(&Monitor{}).Where("id = ?", monitorID).Update("push_started_at", at).Error
+}
+
+// CertAlerted returns the certificate expiry last alerted about for a
+// monitor, or the zero time if there is none
+func (db *DB) CertAlerted(monitorID int) (time.Time, error) {
+	var monitor Monitor
+	if err := db.Select("cert_alerted_expiry").First(&monitor, monitorID).Error; err != nil {
+		return time.Time{}, err
+	}
+	if monitor.CertAlertedExpiry == nil {
+		return time.Time{}, nil
+	}
+	return *monitor.CertAlertedExpiry, nil
+}
+
+// SetCertAlerted records the certificate expiry alerted about for a
+// monitor, nil clears it
+func (db *DB) SetCertAlerted(monitorID int, expiry *time.Time) error {
+	return db.Model(&Monitor{}).Where("id = ?", monitorID).Update("cert_alerted_expiry", expiry).Error
+}
+
+// LastHeartbeat returns when a push monitor last received a heartbeat, or
+// when it was created if it has never received one. Missed heartbeats
+// recorded by the daemon don't count.
+func (db *DB) LastHeartbeat(monitorID int) (time.Time, error) {
+	var check Check
+	found := db.Where("monitor_id = ? AND error_class <> ?", monitorID, types.ErrorClassMissedHeartbeat).
+		Order("timestamp DESC").
+		Limit(1).
+		Find(&check)
+	if found.Error != nil {
+		return time.Time{}, found.Error
+	}
+	if found.RowsAffected > 0 {
+		return check.Timestamp, nil
+	}
+
+	var monitor Monitor
+	if err := db.First(&monitor, monitorID).Error; err != nil {
+		return time.Time{}, err
+	}
+	return monitor.CreatedAt, nil
+}
+
+// LastCheck returns the time of the most recent check recorded for a
+// monitor, or the zero time if there are none
+func (db *DB) LastCheck(monitorID int) (time.Time, error) {
+	var check Check
+	err := db.Where("monitor_id = ?", monitorID).Order("timestamp DESC").Limit(1).Find(&check).Error
+	return check.Timestamp, err
+}
+
+// AddStats records a check and, unless it is a retry, any change in the
+// monitor's status along with the incident it opens, extends or closes
+func (db *DB) AddStats(monitorName string, result types.CheckResult) (types.StateChange, error) {
 	var monitor Monitor
 	if err := db.Where("name = ?", monitorName).First(&monitor).Error; err != nil {
-		return err
+		return types.StateChange{}, err
 	}
 
 	check := Check{
 		MonitorID:    monitor.ID,
-		ResponseTime: responseTime,
-		IsUp:         isUp,
-		CertExpiry:   &certExpiry,
+		ResponseTime: int(result.ResponseTime.Milliseconds()),
+		IsUp:         result.IsUp,
+		Status:       result.Status,
+		CertIssuer:   result.CertIssuer,
+		CertSANs:     result.CertSANs,
+		TLSError:     result.TLSError,
+		Answer:       result.Answer,
+		Error:        result.Error,
+		ErrorClass:   result.ErrorClass,
+		Warning:      result.Warning,
+		Message:      result.Message,
+		Metrics:      result.Metrics,
+		StatusCode:   result.StatusCode,
+		ResponseSize: result.ResponseSize,
+		Protocol:     result.Protocol,
+		DNSTime:      int(result.Timings.DNS.Milliseconds()),
+		ConnectTime:  int(result.Timings.Connect.Milliseconds()),
+		TLSTime:      int(result.Timings.TLS.Milliseconds()),
+		TTFB:         int(result.Timings.TTFB.Milliseconds()),
+		TransferTime: int(result.Timings.Transfer.Milliseconds()),
+		Attempt:      result.Attempt,
+		IsRetry:      result.IsRetry,
+		Maintenance:  result.Maintenance,
 		Timestamp:    time.Now(),
 	}
+	if !result.CertExpiry.IsZero() {
+		check.CertExpiry = &result.CertExpiry
+	}
+
+	var change types.StateChange
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&check).Error; err != nil {
+			return err
+		}
+		if check.IsRetry {
+			return nil
+		}
+
+		var err error
+		change, err = recordState(tx, monitor, check)
+		return err
+	})
+	return change, err
+}
+
+// recordState stores a new MonitorState when the check changes the
+// monitor's status, and opens an incident when it goes down, extends the
+// open incident while it stays down and closes it when it recovers. Checks
+// during maintenance don't open incidents.
+func recordState(tx *gorm.DB, monitor Monitor, check Check) (types.StateChange, error) {
+	change := types.StateChange{To: check.Status}
+
+	var state MonitorState
+	err := tx.Where("monitor_id = ?", monitor.ID).Order("started_at DESC, id DESC").Limit(1).Find(&state).Error
+	if err != nil {
+		return change, err
+	}
+	if state.State != stateActive {
+		change.From = state.State
+	}
+
+	if change.From != change.To {
+		state = MonitorState{MonitorID: monitor.ID, State: check.Status, StartedAt: check.Timestamp}
+		if err := tx.Create(&state).Error; err != nil {
+			return change, err
+		}
+	}
+
+	// Find rather than First, since there usually isn't an open incident
+	var incident Incident
+	found := tx.Where("monitor_id = ? AND ended_at IS NULL", monitor.ID).Limit(1).Find(&incident)
+	if found.Error != nil {
+		return change, found.Error
+	}
+	open := found.RowsAffected > 0
+	down := check.Status == types.StatusDown
+
+	switch {
+	case down && !open && check.Maintenance:
+		return change, nil
+	case down && !open:
+		incident = Incident{
+			MonitorID:  monitor.ID,
+			StartedAt:  check.Timestamp,
+			FirstError: check.Error,
+			ErrorClass: check.ErrorClass,
+			LastError:  check.Error,
+			CheckCount: 1,
+		}
+		err = tx.Create(&incident).Error
+	case down && open:
+		incident.CheckCount++
+		incident.LastError = check.Error
+		err = tx.Save(&incident).Error
+	case open:
+		incident.EndedAt = &check.Timestamp
+		err = tx.Save(&incident).Error
+	default:
+		return change, nil
+	}
+	if err != nil {
+		return change, err
+	}
+
+	recorded := incident.toTypes(monitor)
+	change.Incident = &recorded
+	return change, nil
+}
+
+// ListIncidents returns the most recent incidents, newest first, optionally
+// only for one monitor or only those still ongoing
+func (db *DB) ListIncidents(monitorName string, count int, openOnly bool) ([]types.Incident, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("count must be positive, got %d", count)
+	}
+
+	query := db.Preload("Incidents", func(db *gorm.DB) *gorm.DB {
+		if openOnly {
+			db = db.Where("ended_at IS NULL")
+		}
+		return db.Order("started_at DESC").Limit(count)
+	})
+	if monitorName != "" {
+		query = query.Where("name = ?", monitorName)
+	}
+
+	var monitors []Monitor
+	if err := query.Find(&monitors).Error; err != nil {
+		return nil, err
+	}
+	if monitorName != "" && len(monitors) == 0 {
+		return nil, fmt.Errorf("monitor %s not found", monitorName)
+	}
+
+	var incidents []types.Incident
+	for _, monitor := range monitors {
+		for _, incident := range monitor.Incidents {
+			incidents = append(incidents, incident.toTypes(monitor))
+		}
+	}
+
+	sort.Slice(incidents, func(i, j int) bool {
+		return incidents[i].StartedAt.After(incidents[j].StartedAt)
+	})
+	if len(incidents) > count {
+		incidents = incidents[:count]
+	}
+
+	return incidents, nil
+}
+
+// GetIncident returns an incident along with the checks recorded while it
+// was open, newest first
+func (db *DB) GetIncident(id int) (types.Incident, error) {
+	var incident Incident
+	if err := db.First(&incident, id).Error; err != nil {
+		return types.Incident{}, err
+	}
+
+	var monitor Monitor
+	if err := db.First(&monitor, incident.MonitorID).Error; err != nil {
+		return types.Incident{}, err
+	}
+
+	end := time.Now()
+	if incident.EndedAt != nil {
+		end = *incident.EndedAt
+	}
+
+	var checks []Check
+	if err := db.Where("monitor_id = ? AND timestamp >= ? AND timestamp <= ? AND NOT is_retry", monitor.ID, incident.StartedAt, end).
+		Order("timestamp DESC").
+		Limit(maxIncidentChecks).
+		Find(&checks).Error; err != nil {
+		return types.Incident{}, err
+	}
+
+	result := incident.toTypes(monitor)
+	for _, check := range checks {
+		result.Checks = append(result.Checks, check.toHistoricalStat())
+	}
+
+	return result, nil
+}
+
+// RecordAlert adds an alert to the history and updates the incident's
+// alert policy state for it
+func (db *DB) RecordAlert(monitorID, incidentID int, event string, channels []string, message string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		alert := Alert{
+			MonitorID:  uint(monitorID),
+			IncidentID: uint(incidentID),
+			Event:      event,
+			Channels:   channels,
+			Message:    message,
+		}
+		if err := tx.Create(&alert).Error; err != nil {
+			return err
+		}
+
+		var updates map[string]any
+		switch event {
+		case types.EventDown:
+			updates = map[string]any{"alerted_at": alert.CreatedAt, "last_alert_at": alert.CreatedAt}
+		case types.EventReminder:
+			updates = map[string]any{"last_alert_at": alert.CreatedAt}
+		case types.EventEscalation:
+			updates = map[string]any{"escalated_at": alert.CreatedAt}
+		}
+		if incidentID == 0 || updates == nil {
+			return nil
+		}
+		return tx.Model(&Incident{}).Where("id = ?", incidentID).Updates(updates).Error
+	})
+}
+
+// AcknowledgeIncident acknowledges the monitor's ongoing incident, which
+// stops its reminders and escalation
+func (db *DB) AcknowledgeIncident(monitorName, by string) (types.Incident, error) {
+	var monitor Monitor
+	if err := db.Where("name = ?", monitorName).First(&monitor).Error; err != nil {
+		return types.Incident{}, fmt.Errorf("monitor %s not found", monitorName)
+	}
+
+	var incident Incident
+	err := db.Transaction(func(tx *gorm.DB) error {
+		found := tx.Where("monitor_id = ? AND ended_at IS NULL", monitor.ID).Limit(1).Find(&incident)
+		if found.Error != nil {
+			return found.Error
+		}
+		if found.RowsAffected == 0 {
+			return fmt.Errorf("monitor %s has no ongoing incident", monitorName)
+		}
+		if incident.AcknowledgedAt != nil {
+			return fmt.Errorf("incident #%d was already acknowledged by %s", incident.ID, incident.AcknowledgedBy)
+		}
 
-	return db.Create(&check).Error
+		now := time.Now()
+		incident.AcknowledgedAt = &now
+		incident.AcknowledgedBy = by
+		if err := tx.Save(&incident).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&Alert{
+			MonitorID:  monitor.ID,
+			IncidentID: incident.ID,
+			Event:      types.EventAcknowledged,
+			Message:    fmt.Sprintf("%s acknowledged by %s", monitorName, by),
+		}).Error
+	})
+	if err != nil {
+		return types.Incident{}, err
+	}
+
+	return incident.toTypes(monitor), nil
 }
 
+// ListAlerts returns the most recent alert history, newest first,
+// optionally only for one monitor
+func (db *DB) ListAlerts(monitorName string, count int) ([]types.Alert, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("count must be positive, got %d", count)
+	}
+
+	query := db.Preload("Alerts", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at DESC, id DESC").Limit(count)
+	})
+	if monitorName != "" {
+		query = query.Where("name = ?", monitorName)
+	}
+
+	var monitors []Monitor
+	if err := query.Find(&monitors).Error; err != nil {
+		return nil, err
+	}
+	if monitorName != "" && len(monitors) == 0 {
+		return nil, fmt.Errorf("monitor %s not found", monitorName)
+	}
+
+	var alerts []types.Alert
+	for _, monitor := range monitors {
+		for _, alert := range monitor.Alerts {
+			alerts = append(alerts, alert.toTypes(monitor))
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].CreatedAt.Equal(alerts[j].CreatedAt) {
+			return alerts[i].ID > alerts[j].ID
+		}
+		return alerts[i].CreatedAt.After(alerts[j].CreatedAt)
+	})
+	if len(alerts) > count {
+		alerts = alerts[:count]
+	}
+
+	return alerts, nil
+}
+
+// QueueNotifications adds an event to the outbox once for each channel,
+// due for delivery straight away
+func (db *DB) QueueNotifications(monitorID int, channels []string, event, message string, payload []byte) error {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	notifications := make([]Notification, len(channels))
+	for i, channel := range channels {
+		notifications[i] = Notification{
+			MonitorID:     uint(monitorID),
+			Channel:       strings.ToLower(channel),
+			Event:         event,
+			Message:       message,
+			Payload:       payload,
+			Status:        types.NotificationPending,
+			NextAttemptAt: now,
+		}
+	}
+	return db.Create(&notifications).Error
+}
+
+// DueNotifications returns pending notifications whose next attempt is due,
+// oldest first. A channel gets each monitor's notifications in order, so one
+// isn't due while an earlier one is still pending, even if that is backing
+// off, and a recovery can't overtake the down alert it resolves.
+func (db *DB) DueNotifications(now time.Time, limit int) ([]types.Notification, error) {
+	var notifications []Notification
+	if err := db.Where("status = ? AND next_attempt_at <= ?", types.NotificationPending, now).
+		Where(`NOT EXISTS (SELECT 1 FROM notifications AS earlier WHERE earlier.monitor_id = notifications.monitor_id
+			AND LOWER(earlier.channel) = LOWER(notifications.channel) AND earlier.status = ? AND earlier.id < notifications.id)`,
+			types.NotificationPending).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]types.Notification, len(notifications))
+	for i, n := range notifications {
+		result[i] = n.toTypes(Monitor{})
+		result[i].Payload = n.Payload
+	}
+	return result, nil
+}
+
+// RecordDelivery logs an attempt at delivering a notification and moves it
+// to its new status, retrying at next if it is still pending
+func (db *DB) RecordDelivery(id int, attempt types.DeliveryAttempt, status string, next time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&DeliveryAttempt{
+			NotificationID: uint(id),
+			Attempt:        attempt.Attempt,
+			StatusCode:     attempt.StatusCode,
+			Error:          attempt.Error,
+			Duration:       attempt.Duration,
+		}).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]any{
+			"status":          status,
+			"attempts":        attempt.Attempt,
+			"last_error":      attempt.Error,
+			"next_attempt_at": next,
+		}
+		if status == types.NotificationDelivered {
+			updates["delivered_at"] = time.Now()
+		}
+		return tx.Model(&Notification{}).Where("id = ?", id).Updates(updates).Error
+	})
+}
+
+// ListNotifications returns the most recent notifications with their
+// delivery attempts, newest first, optionally only for one monitor or channel
+func (db *DB) ListNotifications(monitorName, channel string, count int) ([]types.Notification, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("count must be positive, got %d", count)
+	}
+
+	query := db.Preload("Notifications", func(db *gorm.DB) *gorm.DB {
+		if channel != "" {
+			db = db.Where("channel = ?", strings.ToLower(channel))
+		}
+		return db.Order("created_at DESC, id DESC").Limit(count)
+	}).Preload("Notifications.Log", func(db *gorm.DB) *gorm.DB {
+		return db.Order("attempt")
+	})
+	if monitorName != "" {
+		query = query.Where("name = ?", monitorName)
+	}
+
+	var monitors []Monitor
+	if err := query.Find(&monitors).Error; err != nil {
+		return nil, err
+	}
+	if monitorName != "" && len(monitors) == 0 {
+		return nil, fmt.Errorf("monitor %s not found", monitorName)
+	}
+
+	var notifications []types.Notification
+	for _, monitor := range monitors {
+		for _, notification := range monitor.Notifications {
+			notifications = append(notifications, notification.toTypes(monitor))
+		}
+	}
+
+	sort.Slice(notifications, func(i, j int) bool {
+		if notifications[i].CreatedAt.Equal(notifications[j].CreatedAt) {
+			return notifications[i].ID > notifications[j].ID
+		}
+		return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+	})
+	if len(notifications) > count {
+		notifications = notifications[:count]
+	}
+
+	return notifications, nil
+}
+
+// AddMaintenanceWindow stores a new maintenance window
+func (db *DB) AddMaintenanceWindow(w types.MaintenanceWindow) (types.MaintenanceWindow, error) {
+	window := newMaintenanceWindow(w)
+	if err := db.Create(&window).Error; err != nil {
+		return types.MaintenanceWindow{}, err
+	}
+	return window.toTypes(), nil
+}
+
+// ListMaintenanceWindows returns all maintenance windows in the order they
+// were added
+func (db *DB) ListMaintenanceWindows() ([]types.MaintenanceWindow, error) {
+	var windows []MaintenanceWindow
+	if err := db.Order("id").Find(&windows).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]types.MaintenanceWindow, len(windows))
+	for i, w := range windows {
+		result[i] = w.toTypes()
+	}
+	return result, nil
+}
+
+func (db *DB) RemoveMaintenanceWindow(name string) error {
+	result := db.Where("name = ?", name).Delete(&MaintenanceWindow{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("maintenance window %s not found", name)
+	}
+	return nil
+}
+
+// GetStats returns a monitor's latest check and averages. Checks made
+// during maintenance are left out of the averages and uptime.
 func (db *DB) GetStats(monitorName string, duration time.Duration) (types.ServiceStatus, error) {
 	var status types.ServiceStatus
 	status.ServiceName = monitorName
@@ -112,13 +704,15 @@ func (db *DB) GetStats(monitorName string, duration time.Duration) (types.Servic
 		AvgResponseTime float64
 		Uptime24h       float64
 		Uptime30d       float64
+		Degraded24h     float64
+		Degraded30d     float64
 	}
 
 	dayAgo := time.Now().AddDate(0, 0, -1)
 	monthAgo := time.Now().AddDate(0, 0, -30)
 
 	err := db.Model(&Check{}).
-		Where("monitor_id = ? AND timestamp >= ?", monitor.ID, monthAgo).
+		Where("monitor_id = ? AND timestamp >= ? AND NOT is_retry AND NOT maintenance", monitor.ID, monthAgo).
 		Select("COALESCE(AVG(response_time), 0) as avg_response_time").
 		Scan(&stats).Error
 
@@ -126,11 +720,39 @@ func (db *DB) GetStats(monitorName string, duration time.Duration) (types.Servic
 		return status, err
 	}
 
-	var upCount24h, totalCount24h int64
+	// Phase timings are only averaged over successful checks, failed ones
+	// are missing the phases after the failure
+	var timings struct {
+		DNS      float64
+		Connect  float64
+		TLS      float64
+		TTFB     float64
+		Transfer float64
+	}
+	err = db.Model(&Check{}).
+		Where("monitor_id = ? AND timestamp >= ? AND NOT is_retry AND NOT maintenance AND is_up", monitor.ID, monthAgo).
+		Select("COALESCE(AVG(dns_time), 0) as dns, COALESCE(AVG(connect_time), 0) as connect, " +
+			"COALESCE(AVG(tls_time), 0) as tls, COALESCE(AVG(ttfb), 0) as ttfb, COALESCE(AVG(transfer_time), 0) as transfer").
+		Scan(&timings).Error
+
+	if err != nil {
+		return status, err
+	}
+
+	status.AvgTimings = types.Timings{
+		DNS:      msToDuration(timings.DNS),
+		Connect:  msToDuration(timings.Connect),
+		TLS:      msToDuration(timings.TLS),
+		TTFB:     msToDuration(timings.TTFB),
+		Transfer: msToDuration(timings.Transfer),
+	}
+
+	var upCount24h, degradedCount24h, totalCount24h int64
 	err = db.Model(&Check{}).
-		Where("monitor_id = ? AND timestamp >= ?", monitor.ID, dayAgo).
-		Select("COUNT(CASE WHEN is_up THEN 1 END) as up_count, COUNT(*) as total_count").
-		Row().Scan(&upCount24h, &totalCount24h)
+		Where("monitor_id = ? AND timestamp >= ? AND NOT is_retry AND NOT maintenance", monitor.ID, dayAgo).
+		Select("COUNT(CASE WHEN is_up THEN 1 END) as up_count, COUNT(CASE WHEN status = ? THEN 1 END) as degraded_count, "+
+			"COUNT(*) as total_count", types.StatusDegraded).
+		Row().Scan(&upCount24h, &degradedCount24h, &totalCount24h)
 
 	if err != nil {
 		return status, err
@@ -138,13 +760,15 @@ func (db *DB) GetStats(monitorName string, duration time.Duration) (types.Servic
 
 	if totalCount24h > 0 {
 		stats.Uptime24h = float64(upCount24h) * 100.0 / float64(totalCount24h)
+		stats.Degraded24h = float64(degradedCount24h) * 100.0 / float64(totalCount24h)
 	}
 
-	var upCount30d, totalCount30d int64
+	var upCount30d, degradedCount30d, totalCount30d int64
 	err = db.Model(&Check{}).
-		Where("monitor_id = ? AND timestamp >= ?", monitor.ID, monthAgo).
-		Select("COUNT(CASE WHEN is_up THEN 1 END) as up_count, COUNT(*) as total_count").
-		Row().Scan(&upCount30d, &totalCount30d)
+		Where("monitor_id = ? AND timestamp >= ? AND NOT is_retry AND NOT maintenance", monitor.ID, monthAgo).
+		Select("COUNT(CASE WHEN is_up THEN 1 END) as up_count, COUNT(CASE WHEN status = ? THEN 1 END) as degraded_count, "+
+			"COUNT(*) as total_count", types.StatusDegraded).
+		Row().Scan(&upCount30d, &degradedCount30d, &totalCount30d)
 
 	if err != nil {
 		return status, err
@@ -152,10 +776,11 @@ func (db *DB) GetStats(monitorName string, duration time.Duration) (types.Servic
 
 	if totalCount30d > 0 {
 		stats.Uptime30d = float64(upCount30d) * 100.0 / float64(totalCount30d)
+		stats.Degraded30d = float64(degradedCount30d) * 100.0 / float64(totalCount30d)
 	}
 
 	var lastCheck Check
-	if err := db.Where("monitor_id = ?", monitor.ID).
+	if err := db.Where("monitor_id = ? AND NOT is_retry", monitor.ID).
 		Order("timestamp DESC").
 		First(&lastCheck).Error; err != nil && err != gorm.ErrRecordNotFound {
 		return status, err
@@ -165,9 +790,25 @@ func (db *DB) GetStats(monitorName string, duration time.Duration) (types.Servic
 	status.IsActive = monitor.IsActive
 	status.ResponseTime = lastCheck.ResponseTime
 	status.CurrentStatus = lastCheck.IsUp
+	status.Status = lastCheck.Status
+	status.LastError = lastCheck.Error
+	status.LastErrorClass = lastCheck.ErrorClass
+	status.StatusCode = lastCheck.StatusCode
+	status.ResponseSize = lastCheck.ResponseSize
+	status.Protocol = lastCheck.Protocol
+	status.Timings = lastCheck.timings()
+	status.LastWarning = lastCheck.Warning
+	status.LastMessage = lastCheck.Message
+	status.LastAnswer = lastCheck.Answer
+	status.Metrics = lastCheck.Metrics
+	status.CertificateIssuer = lastCheck.CertIssuer
+	status.CertificateSANs = lastCheck.CertSANs
+	status.TLSError = lastCheck.TLSError
 	status.AvgResponseTime = stats.AvgResponseTime
 	status.Uptime24Hours = stats.Uptime24h
 	status.Uptime30Days = stats.Uptime30d
+	status.Degraded24Hours = stats.Degraded24h
+	status.Degraded30Days = stats.Degraded30d
 	if lastCheck.CertExpiry != nil {
 		status.CertificateExpiry = *lastCheck.CertExpiry
 	}
@@ -175,13 +816,20 @@ func (db *DB) GetStats(monitorName string, duration time.Duration) (types.Servic
 	return status, nil
 }
 
-func (db *DB) GetHistoricalStats(monitorID int, count int) ([]types.HistoricalStat, error) {
+// GetHistoricalStats returns the most recent checks for a monitor, newest
+// first. Retry attempts are only included when includeRetries is set.
+func (db *DB) GetHistoricalStats(monitorID int, count int, includeRetries bool) ([]types.HistoricalStat, error) {
 	if count <= 0 {
 		return nil, fmt.Errorf("count must be positive, got %d", count)
 	}
 
+	query := db.Where("monitor_id = ?", monitorID)
+	if !includeRetries {
+		query = query.Where("NOT is_retry")
+	}
+
 	var checks []Check
-	if err := db.Where("monitor_id = ?", monitorID).
+	if err := query.
 		Order("timestamp DESC").
 		Limit(count).
 		Find(&checks).Error; err != nil {
@@ -190,12 +838,12 @@ func (db *DB) GetHistoricalStats(monitorID int, count int) ([]types.HistoricalSt
 
 	stats := make([]types.HistoricalStat, len(checks))
 	for i, check := range checks {
-		stats[i] = types.HistoricalStat{
-			ResponseTime: check.ResponseTime,
-			IsUp:         check.IsUp,
-			Timestamp:    check.Timestamp,
-		}
+		stats[i] = check.toHistoricalStat()
 	}
 
 	return stats, nil
 }
+
+func msToDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
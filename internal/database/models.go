@@ -2,17 +2,60 @@ package database
 
 import (
 	"time"
+
+	"github.com/watzon/go-up/internal/types"
 )
 
 type Monitor struct {
-	ID        uint           `gorm:"primaryKey"`
-	URL       string         `gorm:"uniqueIndex;not null"`
-	Name      string         `gorm:"not null"`
-	IsActive  bool           `gorm:"default:true"`
-	States    []MonitorState `gorm:"foreignKey:MonitorID"`
-	Checks    []Check        `gorm:"foreignKey:MonitorID"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID                uint   `gorm:"primaryKey"`
+	URL               string `gorm:"uniqueIndex:idx_monitor_target;not null"`
+	Name              string `gorm:"not null"`
+	Type              string `gorm:"uniqueIndex:idx_monitor_target;not null;default:http"`
+	Interval          time.Duration
+	Timeout           time.Duration
+	Retries           int
+	RetryInterval     time.Duration
+	Method            string
+	Headers           map[string]string `gorm:"serializer:json"`
+	Body              string
+	AcceptedStatus    []string `gorm:"serializer:json"`
+	NoFollowRedirects bool
+	TLSSkipVerify     bool
+	TLSCABundle       string
+	CertWarnDays      int
+	CertDownDays      int
+	// CertAlertedExpiry is the certificate expiry last alerted about
+	CertAlertedExpiry *time.Time
+	TCPSend           string
+	TCPExpect         string
+	DNSRecordType     string `gorm:"uniqueIndex:idx_monitor_target"`
+	DNSResolver       string
+	DNSExpected       []string `gorm:"serializer:json"`
+	GRPCService       string
+	GRPCTLS           bool
+	Assertions        []types.Assertion `gorm:"serializer:json"`
+	MaxBodySize       int64
+	DegradedThreshold time.Duration
+	PushToken         string `gorm:"index"`
+	PushGrace         time.Duration
+	PushStartedAt     *time.Time // when the running job started, if there is one
+	AlertChannels     []string   `gorm:"serializer:json"`
+	AlertEvents       []string   `gorm:"serializer:json"`
+	AlertSeverity     string
+	AlertRoutingKeys  map[string]string `gorm:"serializer:json"`
+	AlertDelay        time.Duration
+	AlertRepeat       time.Duration
+	EscalateAfter     time.Duration
+	EscalateChannels  []string       `gorm:"serializer:json"`
+	Tags              []string       `gorm:"serializer:json"`
+	IsActive          bool           `gorm:"default:true"`
+	States            []MonitorState `gorm:"foreignKey:MonitorID"`
+	Checks            []Check        `gorm:"foreignKey:MonitorID"`
+	Incidents         []Incident     `gorm:"foreignKey:MonitorID"`
+	Alerts            []Alert        `gorm:"foreignKey:MonitorID"`
+	Notifications     []Notification `gorm:"foreignKey:MonitorID"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
 
 type MonitorState struct {
@@ -24,13 +67,343 @@ type MonitorState struct {
 	UpdatedAt time.Time
 }
 
+type Incident struct {
+	ID         uint      `gorm:"primaryKey"`
+	MonitorID  uint      `gorm:"index"`
+	StartedAt  time.Time `gorm:"not null"`
+	EndedAt    *time.Time
+	FirstError string
+	ErrorClass string
+	LastError  string
+	CheckCount int
+	// Alert policy state, nil until the alert is sent
+	AlertedAt      *time.Time
+	LastAlertAt    *time.Time
+	EscalatedAt    *time.Time
+	AcknowledgedAt *time.Time
+	AcknowledgedBy string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Notification is an outbox entry, an event waiting to be delivered to a
+// channel or the record of its delivery
+type Notification struct {
+	ID            uint `gorm:"primaryKey"`
+	MonitorID     uint `gorm:"index"`
+	Channel       string
+	Event         string
+	Message       string
+	Payload       []byte
+	Status        string `gorm:"index"`
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string
+	DeliveredAt   *time.Time
+	Log           []DeliveryAttempt `gorm:"foreignKey:NotificationID"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type DeliveryAttempt struct {
+	ID             uint `gorm:"primaryKey"`
+	NotificationID uint `gorm:"index"`
+	Attempt        int
+	StatusCode     int
+	Error          string
+	Duration       time.Duration
+	CreatedAt      time.Time
+}
+
+// Alert records a notification sent for a monitor, or an acknowledgement
+type Alert struct {
+	ID         uint `gorm:"primaryKey"`
+	MonitorID  uint `gorm:"index"`
+	IncidentID uint `gorm:"index"`
+	Event      string
+	Channels   []string `gorm:"serializer:json"`
+	Message    string
+	CreatedAt  time.Time
+}
+
+// MaintenanceWindow is a one-off or recurring maintenance period. Start and
+// End are only set for one-off windows.
+type MaintenanceWindow struct {
+	ID        uint     `gorm:"primaryKey"`
+	Name      string   `gorm:"uniqueIndex;not null"`
+	Monitors  []string `gorm:"serializer:json"`
+	Tags      []string `gorm:"serializer:json"`
+	Mode      string   `gorm:"not null"`
+	Start     *time.Time
+	End       *time.Time
+	Schedule  string
+	Duration  time.Duration
+	Timezone  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
 type Check struct {
 	ID           uint `gorm:"primaryKey"`
 	MonitorID    uint
 	Timestamp    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	ResponseTime int
 	IsUp         bool
+	Status       string `gorm:"not null;default:''"`
 	CertExpiry   *time.Time
+	CertIssuer   string
+	CertSANs     []string `gorm:"serializer:json"`
+	TLSError     string
+	Answer       string
+	Error        string
+	ErrorClass   string
+	Warning      string
+	Message      string
+	Metrics      []types.Metric `gorm:"serializer:json"`
+	StatusCode   int
+	ResponseSize int64
+	Protocol     string
+	DNSTime      int
+	ConnectTime  int
+	TLSTime      int
+	TTFB         int
+	TransferTime int
+	Attempt      int
+	IsRetry      bool `gorm:"not null;default:false"`
+	Maintenance  bool `gorm:"not null;default:false"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
+
+func newMonitor(m types.Monitor) Monitor {
+	return Monitor{
+		Name:              m.Name,
+		URL:               m.URL,
+		Type:              m.Type,
+		Interval:          m.Interval,
+		Timeout:           m.Timeout,
+		Retries:           m.Retries,
+		RetryInterval:     m.RetryInterval,
+		Method:            m.Method,
+		Headers:           m.Headers,
+		Body:              m.Body,
+		AcceptedStatus:    m.AcceptedStatus,
+		NoFollowRedirects: m.NoFollowRedirects,
+		TLSSkipVerify:     m.TLSSkipVerify,
+		TLSCABundle:       m.TLSCABundle,
+		CertWarnDays:      m.CertWarnDays,
+		CertDownDays:      m.CertDownDays,
+		TCPSend:           m.TCPSend,
+		TCPExpect:         m.TCPExpect,
+		DNSRecordType:     m.DNSRecordType,
+		DNSResolver:       m.DNSResolver,
+		DNSExpected:       m.DNSExpected,
+		GRPCService:       m.GRPCService,
+		GRPCTLS:           m.GRPCTLS,
+		Assertions:        m.Assertions,
+		MaxBodySize:       m.MaxBodySize,
+		DegradedThreshold: m.DegradedThreshold,
+		PushToken:         m.PushToken,
+		PushGrace:         m.PushGrace,
+		AlertChannels:     m.AlertChannels,
+		AlertEvents:       m.AlertEvents,
+		AlertSeverity:     m.AlertSeverity,
+		AlertRoutingKeys:  m.AlertRoutingKeys,
+		AlertDelay:        m.AlertDelay,
+		AlertRepeat:       m.AlertRepeat,
+		EscalateAfter:     m.EscalateAfter,
+		EscalateChannels:  m.EscalateChannels,
+		Tags:              m.Tags,
+		IsActive:          m.IsActive,
+	}
+}
+
+func (m Monitor) toTypes() types.Monitor {
+	return types.Monitor{
+		ID:                int(m.ID),
+		Name:              m.Name,
+		URL:               m.URL,
+		Type:              m.Type,
+		Interval:          m.Interval,
+		Timeout:           m.Timeout,
+		Retries:           m.Retries,
+		RetryInterval:     m.RetryInterval,
+		Method:            m.Method,
+		Headers:           m.Headers,
+		Body:              m.Body,
+		AcceptedStatus:    m.AcceptedStatus,
+		NoFollowRedirects: m.NoFollowRedirects,
+		TLSSkipVerify:     m.TLSSkipVerify,
+		TLSCABundle:       m.TLSCABundle,
+		CertWarnDays:      m.CertWarnDays,
+		CertDownDays:      m.CertDownDays,
+		TCPSend:           m.TCPSend,
+		TCPExpect:         m.TCPExpect,
+		DNSRecordType:     m.DNSRecordType,
+		DNSResolver:       m.DNSResolver,
+		DNSExpected:       m.DNSExpected,
+		GRPCService:       m.GRPCService,
+		GRPCTLS:           m.GRPCTLS,
+		Assertions:        m.Assertions,
+		MaxBodySize:       m.MaxBodySize,
+		DegradedThreshold: m.DegradedThreshold,
+		PushToken:         m.PushToken,
+		PushGrace:         m.PushGrace,
+		AlertChannels:     m.AlertChannels,
+		AlertEvents:       m.AlertEvents,
+		AlertSeverity:     m.AlertSeverity,
+		AlertRoutingKeys:  m.AlertRoutingKeys,
+		AlertDelay:        m.AlertDelay,
+		AlertRepeat:       m.AlertRepeat,
+		EscalateAfter:     m.EscalateAfter,
+		EscalateChannels:  m.EscalateChannels,
+		Tags:              m.Tags,
+		IsActive:          m.IsActive,
+	}
+}
+
+func (c Check) timings() types.Timings {
+	return types.Timings{
+		DNS:      time.Duration(c.DNSTime) * time.Millisecond,
+		Connect:  time.Duration(c.ConnectTime) * time.Millisecond,
+		TLS:      time.Duration(c.TLSTime) * time.Millisecond,
+		TTFB:     time.Duration(c.TTFB) * time.Millisecond,
+		Transfer: time.Duration(c.TransferTime) * time.Millisecond,
+	}
+}
+
+func (c Check) toHistoricalStat() types.HistoricalStat {
+	return types.HistoricalStat{
+		ResponseTime: c.ResponseTime,
+		IsUp:         c.IsUp,
+		Status:       c.Status,
+		Answer:       c.Answer,
+		Error:        c.Error,
+		ErrorClass:   c.ErrorClass,
+		Warning:      c.Warning,
+		Message:      c.Message,
+		Metrics:      c.Metrics,
+		StatusCode:   c.StatusCode,
+		ResponseSize: c.ResponseSize,
+		Protocol:     c.Protocol,
+		Timings:      c.timings(),
+		Attempt:      c.Attempt,
+		IsRetry:      c.IsRetry,
+		Timestamp:    c.Timestamp,
+	}
+}
+
+func (i Incident) toTypes(monitor Monitor) types.Incident {
+	incident := types.Incident{
+		ID:          int(i.ID),
+		MonitorID:   int(i.MonitorID),
+		MonitorName: monitor.Name,
+		MonitorURL:  monitor.URL,
+		StartedAt:   i.StartedAt,
+		Duration:    time.Since(i.StartedAt),
+		FirstError:  i.FirstError,
+		ErrorClass:  i.ErrorClass,
+		LastError:   i.LastError,
+		CheckCount:  i.CheckCount,
+	}
+	if i.EndedAt != nil {
+		incident.EndedAt = *i.EndedAt
+		incident.Duration = i.EndedAt.Sub(i.StartedAt)
+	}
+	if i.AlertedAt != nil {
+		incident.AlertedAt = *i.AlertedAt
+	}
+	if i.LastAlertAt != nil {
+		incident.LastAlertAt = *i.LastAlertAt
+	}
+	if i.EscalatedAt != nil {
+		incident.EscalatedAt = *i.EscalatedAt
+	}
+	if i.AcknowledgedAt != nil {
+		incident.AcknowledgedAt = *i.AcknowledgedAt
+		incident.AcknowledgedBy = i.AcknowledgedBy
+	}
+	return incident
+}
+
+func (a Alert) toTypes(monitor Monitor) types.Alert {
+	return types.Alert{
+		ID:          int(a.ID),
+		MonitorID:   int(a.MonitorID),
+		MonitorName: monitor.Name,
+		IncidentID:  int(a.IncidentID),
+		Event:       a.Event,
+		Channels:    a.Channels,
+		Message:     a.Message,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+func (n Notification) toTypes(monitor Monitor) types.Notification {
+	notification := types.Notification{
+		ID:            int(n.ID),
+		MonitorID:     int(n.MonitorID),
+		MonitorName:   monitor.Name,
+		Channel:       n.Channel,
+		Event:         n.Event,
+		Message:       n.Message,
+		Status:        n.Status,
+		Attempts:      n.Attempts,
+		NextAttemptAt: n.NextAttemptAt,
+		LastError:     n.LastError,
+		CreatedAt:     n.CreatedAt,
+	}
+	if n.DeliveredAt != nil {
+		notification.DeliveredAt = *n.DeliveredAt
+	}
+	for _, attempt := range n.Log {
+		notification.Log = append(notification.Log, types.DeliveryAttempt{
+			Attempt:    attempt.Attempt,
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+			Duration:   attempt.Duration,
+			CreatedAt:  attempt.CreatedAt,
+		})
+	}
+	return notification
+}
+
+func newMaintenanceWindow(w types.MaintenanceWindow) MaintenanceWindow {
+	window := MaintenanceWindow{
+		Name:     w.Name,
+		Monitors: w.Monitors,
+		Tags:     w.Tags,
+		Mode:     w.Mode,
+		Schedule: w.Schedule,
+		Duration: w.Duration,
+		Timezone: w.Timezone,
+	}
+	if !w.Start.IsZero() {
+		window.Start = &w.Start
+	}
+	if !w.End.IsZero() {
+		window.End = &w.End
+	}
+	return window
+}
+
+func (w MaintenanceWindow) toTypes() types.MaintenanceWindow {
+	window := types.MaintenanceWindow{
+		ID:        int(w.ID),
+		Name:      w.Name,
+		Monitors:  w.Monitors,
+		Tags:      w.Tags,
+		Mode:      w.Mode,
+		Schedule:  w.Schedule,
+		Duration:  w.Duration,
+		Timezone:  w.Timezone,
+		CreatedAt: w.CreatedAt,
+	}
+	if w.Start != nil {
+		window.Start = *w.Start
+	}
+	if w.End != nil {
+		window.End = *w.End
+	}
+	return window
+}
@@ -0,0 +1,295 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// defaultTelegramURL is the Bot API base URL used unless the channel sets one
+const defaultTelegramURL = "https://api.telegram.org"
+
+// Default chat message templates. Slack's is the message text shown above
+// the attachment, Discord's the embed's description and Telegram's the
+// whole HTML message.
+const (
+	defaultSlackTemplate    = `{{emoji .}} {{.Message}}`
+	defaultDiscordTemplate  = `{{.URL}}`
+	defaultTelegramTemplate = `{{emoji .}} <b>{{.Message}}</b>
+{{with link .}}<a href="{{.}}">{{.}}</a>{{else}}<code>{{.URL}}</code>{{end}}
+
+<b>Status:</b> {{upper .Status}}
+<b>Response time:</b> {{.ResponseTime}} ms
+<b>Uptime (24h):</b> {{percent .Uptime24Hours}}
+<b>Uptime (30d):</b> {{percent .Uptime30Days}}
+{{- if .Error}}
+<b>Error:</b> [{{.ErrorClass}}] {{.Error}}{{end}}
+{{- if .CertExpiry}}
+<b>Certificate expires:</b> {{.CertExpiry.Format "2006-01-02"}}{{end}}
+`
+)
+
+// Status colours shared by the chat formats
+const (
+	colorUp       = "#2eb886"
+	colorDegraded = "#daa038"
+	colorDown     = "#a30200"
+)
+
+// statusColor picks the colour for an event. Certificate expiry is shown
+// as a warning unless the monitor is also down.
+func statusColor(event Event) string {
+	switch {
+	case event.Status == types.StatusDown:
+		return colorDown
+	case event.Status == types.StatusDegraded, event.Type == types.EventCertExpiry:
+		return colorDegraded
+	default:
+		return colorUp
+	}
+}
+
+func statusEmoji(event Event) string {
+	switch statusColor(event) {
+	case colorDown:
+		return "🔴"
+	case colorDegraded:
+		return "🟡"
+	default:
+		return "🟢"
+	}
+}
+
+// link returns the monitor's URL if chat clients can open it
+func link(event Event) string {
+	if strings.HasPrefix(event.URL, "http://") || strings.HasPrefix(event.URL, "https://") {
+		return event.URL
+	}
+	return ""
+}
+
+// chatField is a label and value shown in a chat message
+type chatField struct {
+	name  string
+	value string
+}
+
+// chatFields lists the details every chat format shows
+func chatFields(event Event) []chatField {
+	fields := []chatField{
+		{"Status", strings.ToUpper(event.Status)},
+		{"Response time", fmt.Sprintf("%d ms", event.ResponseTime)},
+		{"Uptime (24h)", fmt.Sprintf("%.2f%%", event.Uptime24Hours)},
+		{"Uptime (30d)", fmt.Sprintf("%.2f%%", event.Uptime30Days)},
+	}
+	if event.Error != "" {
+		fields = append(fields, chatField{"Error", fmt.Sprintf("[%s] %s", event.ErrorClass, event.Error)})
+	}
+	if event.CertExpiry != nil {
+		fields = append(fields, chatField{"Certificate expires", event.CertExpiry.Format("2006-01-02")})
+	}
+	return fields
+}
+
+// slack posts events to a Slack or Mattermost incoming webhook as a
+// colour-coded attachment
+type slack struct {
+	url    string
+	text   *template.Template
+	client *http.Client
+}
+
+func newSlack(cfg ChannelConfig) (Notifier, error) {
+	if err := validateURL(cfg.URL); err != nil {
+		return nil, err
+	}
+	text, err := parseTemplate("message", cfg.Template, defaultSlackTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &slack{url: cfg.URL, text: text, client: &http.Client{}}, nil
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Fallback  string       `json:"fallback"`
+	Color     string       `json:"color"`
+	Title     string       `json:"title"`
+	TitleLink string       `json:"title_link,omitempty"`
+	Text      string       `json:"text,omitempty"`
+	Fields    []slackField `json:"fields"`
+	Footer    string       `json:"footer"`
+	Timestamp int64        `json:"ts"`
+}
+
+func (s *slack) Notify(ctx context.Context, event Event) error {
+	text, err := render(s.text, event)
+	if err != nil {
+		return fmt.Errorf("rendering message template: %w", err)
+	}
+
+	attachment := slackAttachment{
+		Fallback:  event.Message,
+		Color:     statusColor(event),
+		Title:     event.Message,
+		TitleLink: link(event),
+		Text:      event.URL,
+		Footer:    "go-up",
+		Timestamp: event.Time.Unix(),
+	}
+	for _, f := range chatFields(event) {
+		attachment.Fields = append(attachment.Fields, slackField{
+			Title: f.name,
+			Value: f.value,
+			Short: f.name != "Error",
+		})
+	}
+
+	return postJSON(ctx, s.client, s.url, map[string]any{
+		"text":        text,
+		"attachments": []slackAttachment{attachment},
+	}, nil)
+}
+
+// discord posts events to a Discord webhook as an embed
+type discord struct {
+	url         string
+	description *template.Template
+	client      *http.Client
+}
+
+func newDiscord(cfg ChannelConfig) (Notifier, error) {
+	if err := validateURL(cfg.URL); err != nil {
+		return nil, err
+	}
+	description, err := parseTemplate("message", cfg.Template, defaultDiscordTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &discord{url: cfg.URL, description: description, client: &http.Client{}}, nil
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields"`
+	Footer      struct {
+		Text string `json:"text"`
+	} `json:"footer"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (d *discord) Notify(ctx context.Context, event Event) error {
+	description, err := render(d.description, event)
+	if err != nil {
+		return fmt.Errorf("rendering message template: %w", err)
+	}
+
+	var color int
+	fmt.Sscanf(statusColor(event), "#%x", &color)
+
+	embed := discordEmbed{
+		Title:       truncate(event.Message, 256),
+		URL:         link(event),
+		Description: truncate(description, 4096),
+		Color:       color,
+		Timestamp:   event.Time.UTC().Format(time.RFC3339),
+	}
+	embed.Footer.Text = "go-up"
+	for _, f := range chatFields(event) {
+		embed.Fields = append(embed.Fields, discordField{
+			Name:   f.name,
+			Value:  truncate(f.value, 1024),
+			Inline: f.name != "Error",
+		})
+	}
+
+	return postJSON(ctx, d.client, d.url, map[string]any{
+		"embeds": []discordEmbed{embed},
+	}, nil)
+}
+
+// telegram sends events as HTML formatted messages through the Bot API
+type telegram struct {
+	url    string
+	chatID string
+	text   *htmltemplate.Template
+	client *http.Client
+}
+
+func newTelegram(cfg ChannelConfig) (Notifier, error) {
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("telegram channel needs a bot token")
+	}
+	if cfg.ChatID == "" {
+		return nil, fmt.Errorf("telegram channel needs a chat ID")
+	}
+
+	base := cfg.URL
+	if base == "" {
+		base = defaultTelegramURL
+	}
+	if err := validateURL(base); err != nil {
+		return nil, err
+	}
+
+	text, err := parseHTMLTemplate("message", cfg.Template, defaultTelegramTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &telegram{
+		url:    strings.TrimRight(base, "/") + "/bot" + cfg.Token + "/sendMessage",
+		chatID: cfg.ChatID,
+		text:   text,
+		client: &http.Client{},
+	}, nil
+}
+
+func (t *telegram) Notify(ctx context.Context, event Event) error {
+	text, err := render(t.text, event)
+	if err != nil {
+		return fmt.Errorf("rendering message template: %w", err)
+	}
+
+	err = postJSON(ctx, t.client, t.url, map[string]any{
+		"chat_id":                  t.chatID,
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}, nil)
+	// Keep the bot token, which is part of the URL, out of the logs
+	if urlErr, ok := err.(*url.Error); ok {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// truncate shortens s to at most n runes, as chat APIs reject long fields
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
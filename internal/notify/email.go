@@ -0,0 +1,296 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TLS modes for email channels
+const (
+	emailStartTLS = "starttls"
+	emailTLS      = "tls"
+	emailNoTLS    = "none"
+)
+
+// Default email templates
+const defaultEmailSubject = `[go-up] {{.Message}}`
+
+const defaultEmailText = `{{.Message}}
+
+Monitor: {{.Monitor}}
+URL:     {{.URL}}
+Status:  {{.Status}}{{if .PreviousStatus}} (was {{.PreviousStatus}}){{end}}
+{{- if .Error}}
+Error:   [{{.ErrorClass}}] {{.Error}}{{end}}
+{{- if .CertExpiry}}
+Certificate expires: {{.CertExpiry.Format "2006-01-02"}}{{end}}
+Time:    {{.Time.Format "2006-01-02 15:04:05 MST"}}
+{{- if .Recent}}
+
+Recent checks:
+{{- range .Recent}}
+  {{.Timestamp.Local.Format "2006-01-02 15:04:05"}}  {{printf "%-8s" .Status}} {{printf "%6d" .ResponseTime}}ms{{if .Error}}  {{.Error}}{{end}}{{end}}
+{{- end}}
+`
+
+const defaultEmailHTML = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>{{.Message}}</h2>
+<table>
+<tr><th align="left">Monitor</th><td>{{.Monitor}}</td></tr>
+<tr><th align="left">URL</th><td>{{.URL}}</td></tr>
+<tr><th align="left">Status</th><td>{{.Status}}{{if .PreviousStatus}} (was {{.PreviousStatus}}){{end}}</td></tr>
+{{- if .Error}}
+<tr><th align="left">Error</th><td>[{{.ErrorClass}}] {{.Error}}</td></tr>
+{{- end}}
+{{- if .CertExpiry}}
+<tr><th align="left">Certificate expires</th><td>{{.CertExpiry.Format "2006-01-02"}}</td></tr>
+{{- end}}
+<tr><th align="left">Time</th><td>{{.Time.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+</table>
+{{- if .Recent}}
+<h3>Recent checks</h3>
+<table>
+<tr><th align="left">Time</th><th align="left">Status</th><th align="right">Response</th><th align="left">Error</th></tr>
+{{- range .Recent}}
+<tr><td>{{.Timestamp.Local.Format "2006-01-02 15:04:05"}}</td><td>{{.Status}}</td><td align="right">{{.ResponseTime}} ms</td><td>{{.Error}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`
+
+// email sends events as multipart plain text and HTML messages through an
+// SMTP server
+type email struct {
+	addr       string
+	host       string
+	tlsMode    string
+	skipVerify bool
+	username   string
+	password   string
+	from       string
+	to         []string
+	subject    *template.Template
+	text       *template.Template
+	html       *htmltemplate.Template
+}
+
+func newEmail(cfg ChannelConfig) (Notifier, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("email channel needs an SMTP host")
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", cfg.From, err)
+	}
+	if len(cfg.To) == 0 {
+		return nil, fmt.Errorf("email channel needs at least one recipient")
+	}
+	for _, to := range cfg.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", to, err)
+		}
+	}
+
+	e := &email{
+		host:       cfg.Host,
+		tlsMode:    strings.ToLower(cfg.TLS),
+		skipVerify: cfg.SkipVerify,
+		username:   cfg.Username,
+		password:   cfg.Password,
+		from:       cfg.From,
+		to:         cfg.To,
+	}
+	if e.tlsMode == "" {
+		e.tlsMode = emailStartTLS
+	}
+
+	var err error
+	if e.subject, err = parseTemplate("subject", cfg.Subject, defaultEmailSubject); err != nil {
+		return nil, err
+	}
+	if e.text, err = parseTemplate("text", cfg.Template, defaultEmailText); err != nil {
+		return nil, err
+	}
+	if e.html, err = parseHTMLTemplate("html", cfg.HTMLTemplate, defaultEmailHTML); err != nil {
+		return nil, err
+	}
+
+	port := cfg.Port
+	switch e.tlsMode {
+	case emailStartTLS, emailNoTLS:
+		if port == 0 {
+			port = 587
+		}
+	case emailTLS:
+		if port == 0 {
+			port = 465
+		}
+	default:
+		return nil, fmt.Errorf("unknown TLS mode %q, expected starttls, tls or none", cfg.TLS)
+	}
+	e.addr = net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+
+	return e, nil
+}
+
+func (e *email) Notify(ctx context.Context, event Event) error {
+	msg, err := e.message(event)
+	if err != nil {
+		return err
+	}
+
+	client, err := e.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if e.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.username, e.password, e.host)); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	from, _ := mail.ParseAddress(e.from)
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		addr, _ := mail.ParseAddress(to)
+		if err := client.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial connects to the SMTP server, upgrading the connection as the
+// channel's TLS mode requires
+func (e *email) dial(ctx context.Context) (*smtp.Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	tlsConfig := &tls.Config{ServerName: e.host, InsecureSkipVerify: e.skipVerify}
+	if e.tlsMode == emailTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if e.tlsMode == emailStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("server %s does not support STARTTLS", e.addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// message builds the MIME message for an event
+func (e *email) message(event Event) ([]byte, error) {
+	subject, err := render(e.subject, event)
+	if err != nil {
+		return nil, fmt.Errorf("rendering subject template: %w", err)
+	}
+	text, err := render(e.text, event)
+	if err != nil {
+		return nil, fmt.Errorf("rendering text template: %w", err)
+	}
+	html, err := render(e.html, event)
+	if err != nil {
+		return nil, fmt.Errorf("rendering html template: %w", err)
+	}
+
+	var parts bytes.Buffer
+	body := multipart.NewWriter(&parts)
+
+	headers := []string{
+		"From: " + e.from,
+		"To: " + strings.Join(e.to, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject), " ")),
+		"Date: " + event.Time.Format(time.RFC1123Z),
+		"Message-ID: " + messageID(e.host),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + body.Boundary(),
+	}
+
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", []byte(text)},
+		{"text/html; charset=utf-8", []byte(html)},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	msg := []byte(strings.Join(headers, "\r\n") + "\r\n\r\n")
+	return append(msg, parts.Bytes()...), nil
+}
+
+func messageID(host string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), host)
+}
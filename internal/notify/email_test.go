@@ -0,0 +1,268 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// sentMail is a message received by an smtpServer
+type sentMail struct {
+	auth string
+	tls  bool
+	from string
+	to   []string
+	data []byte
+}
+
+// smtpServer is a minimal in-process SMTP server that accepts every
+// message, offering STARTTLS when it has a TLS config
+type smtpServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	received  chan sentMail
+}
+
+func startSMTPServer(t *testing.T, tlsConfig *tls.Config) *smtpServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpServer{listener: listener, tlsConfig: tlsConfig, received: make(chan sentMail, 1)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	tp := textproto.NewConn(conn)
+	var msg sentMail
+	tp.PrintfLine("220 localhost ESMTP test")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-localhost")
+			if s.tlsConfig != nil && !msg.tls {
+				tp.PrintfLine("250-STARTTLS")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 ready to start TLS")
+			conn = tls.Server(conn, s.tlsConfig)
+			tp = textproto.NewConn(conn)
+			msg.tls = true
+		case "AUTH":
+			_, encoded, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(encoded)
+			msg.auth = string(decoded)
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			msg.data, err = tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			tp.PrintfLine("250 queued")
+			s.received <- msg
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+// parseMail returns a message's headers and its text and HTML parts
+func parseMail(t *testing.T, data []byte) (mail.Header, string, string) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(data))))
+	if err != nil {
+		t.Fatalf("reading message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+
+	parts := make(map[string]string)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+		content, _ := io.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(content)
+	}
+	return msg.Header, parts["text/plain"], parts["text/html"]
+}
+
+func emailEvent() Event {
+	return Event{
+		Type:       types.EventDown,
+		MonitorID:  1,
+		Monitor:    "api",
+		URL:        "https://api.example.com",
+		Status:     types.StatusDown,
+		Message:    "api is down: connection refused — again",
+		Error:      "connection refused",
+		ErrorClass: types.ErrorClassConnection,
+		Time:       time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestEmailSendsMessage(t *testing.T) {
+	server := startSMTPServer(t, nil)
+
+	notifier, err := newEmail(ChannelConfig{
+		Type:     "email",
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		TLS:      emailNoTLS,
+		Username: "alerts",
+		Password: "hunter2",
+		From:     "go-up <alerts@example.com>",
+		To:       []string{"ops@example.com", "Oncall <oncall@example.com>"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(context.Background(), emailEvent()); err != nil {
+		t.Fatalf("Notify() = %v", err)
+	}
+
+	msg := <-server.received
+	if msg.auth != "\x00alerts\x00hunter2" {
+		t.Errorf("auth = %q, want alerts/hunter2", msg.auth)
+	}
+	if msg.from != "alerts@example.com" {
+		t.Errorf("MAIL FROM = %q, want alerts@example.com", msg.from)
+	}
+	if strings.Join(msg.to, ",") != "ops@example.com,oncall@example.com" {
+		t.Errorf("RCPT TO = %v, want ops@example.com and oncall@example.com", msg.to)
+	}
+
+	header, text, html := parseMail(t, msg.data)
+	subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	if err != nil || subject != "[go-up] api is down: connection refused — again" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if header.Get("To") != "ops@example.com, Oncall <oncall@example.com>" {
+		t.Errorf("To = %q", header.Get("To"))
+	}
+	for _, want := range []string{"Monitor: api", "Error:   [connection] connection refused"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text part doesn't contain %q:\n%s", want, text)
+		}
+	}
+	if !strings.Contains(html, "<h2>api is down: connection refused — again</h2>") {
+		t.Errorf("html part doesn't contain the message:\n%s", html)
+	}
+}
+
+func TestEmailStartTLS(t *testing.T) {
+	// Borrow httptest's self-signed certificate for the SMTP server
+	https := httptest.NewTLSServer(nil)
+	defer https.Close()
+	server := startSMTPServer(t, &tls.Config{Certificates: https.TLS.Certificates})
+
+	cfg := ChannelConfig{
+		Type: "email",
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "alerts@example.com",
+		To:   []string{"ops@example.com"},
+	}
+
+	notifier, err := newEmail(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(context.Background(), emailEvent()); err == nil {
+		t.Error("Notify() succeeded with an untrusted certificate")
+	}
+
+	cfg.SkipVerify = true
+	notifier, err = newEmail(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(context.Background(), emailEvent()); err != nil {
+		t.Fatalf("Notify() = %v", err)
+	}
+	if msg := <-server.received; !msg.tls {
+		t.Error("message was sent without STARTTLS")
+	}
+}
+
+func TestEmailRequiresStartTLS(t *testing.T) {
+	server := startSMTPServer(t, nil)
+
+	notifier, err := newEmail(ChannelConfig{
+		Type: "email",
+		Host: "127.0.0.1",
+		Port: server.port(),
+		From: "alerts@example.com",
+		To:   []string{"ops@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = notifier.Notify(context.Background(), emailEvent())
+	if err == nil || !strings.Contains(err.Error(), "does not support STARTTLS") {
+		t.Fatalf("Notify() = %v, want an error about STARTTLS", err)
+	}
+	if !strings.Contains(err.Error(), strconv.Itoa(server.port())) {
+		t.Errorf("error %q doesn't name the server", err)
+	}
+}
@@ -0,0 +1,213 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// sendTimeout bounds how long a single notification may take
+const sendTimeout = 15 * time.Second
+
+// Event is a change in a monitor's state that channels are notified about
+type Event struct {
+	ID             string     `json:"id"`
+	Type           string     `json:"event"`
+	MonitorID      int        `json:"monitor_id"`
+	Monitor        string     `json:"monitor"`
+	URL            string     `json:"url"`
+	Status         string     `json:"status"`
+	PreviousStatus string     `json:"previous_status,omitempty"`
+	Message        string     `json:"message"`
+	Error          string     `json:"error,omitempty"`
+	ErrorClass     string     `json:"error_class,omitempty"`
+	ResponseTime   int        `json:"response_time_ms"`
+	CertExpiry     *time.Time `json:"cert_expiry,omitempty"`
+	IncidentID     int        `json:"incident_id,omitempty"`
+	Uptime24Hours  float64    `json:"uptime_24h"`
+	Uptime30Days   float64    `json:"uptime_30d"`
+	Severity       string     `json:"severity,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	Time           time.Time  `json:"time"`
+
+	// IncidentDuration is how long the monitor has been down, or was down
+	// for recovery events
+	IncidentDuration time.Duration `json:"-"`
+
+	// The monitor's settings and current status, for templates
+	MonitorConfig types.Monitor       `json:"-"`
+	ServiceStatus types.ServiceStatus `json:"-"`
+
+	// RoutingKeys override the routing or API key of incident management
+	// channels, by channel name. RoutingKey is the override for the channel
+	// being delivered to, which the Dispatcher picks out.
+	RoutingKeys map[string]string `json:"-"`
+	RoutingKey  string            `json:"-"`
+
+	// Recent checks, newest first, for channels that show some history
+	Recent []types.HistoricalStat `json:"-"`
+}
+
+// Notifier delivers events to a notification channel
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Resolver is implemented by channels whose alerts stay open until they are
+// resolved, so alerts nothing else would resolve can be
+type Resolver interface {
+	Resolve(ctx context.Context, event Event) error
+}
+
+// ChannelConfig configures a notification channel. Which fields are used
+// depends on the channel type.
+type ChannelConfig struct {
+	Type    string
+	URL     string
+	Method  string
+	Headers map[string]string
+	Body    string
+	// Secret signs webhook requests, see pkg/webhook
+	Secret string
+
+	// Message templates, rendered with the Event. Template is the main text
+	// of the message, HTMLTemplate and Subject are used by email channels.
+	Template     string
+	HTMLTemplate string `mapstructure:"html_template"`
+	Subject      string
+
+	// Telegram bot token and chat. URL overrides the Bot API base URL.
+	Token  string
+	ChatID string `mapstructure:"chat_id"`
+
+	// PagerDuty routing key, Opsgenie API key and the default severity for
+	// down events. URL overrides the API endpoint.
+	RoutingKey string `mapstructure:"routing_key"`
+	APIKey     string `mapstructure:"api_key"`
+	Severity   string
+
+	// SMTP settings for email channels. TLS is starttls (the default), tls
+	// for implicit TLS or none. SkipVerify is meant for local test servers.
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	From       string
+	To         []string
+	TLS        string
+	SkipVerify bool `mapstructure:"skip_verify"`
+}
+
+// factories maps each channel type to the function that creates it
+var factories = map[string]func(cfg ChannelConfig) (Notifier, error){
+	"webhook":    newWebhook,
+	"email":      newEmail,
+	"slack":      newSlack,
+	"mattermost": newSlack,
+	"discord":    newDiscord,
+	"telegram":   newTelegram,
+	"pagerduty":  newPagerDuty,
+	"opsgenie":   newOpsgenie,
+}
+
+// New creates the notifier for a channel
+func New(cfg ChannelConfig) (Notifier, error) {
+	factory, ok := factories[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel type %q", cfg.Type)
+	}
+	return factory(cfg)
+}
+
+// Dispatcher sends events to the configured channels by name
+type Dispatcher struct {
+	channels map[string]Notifier
+	types    map[string]string
+}
+
+// NewDispatcher creates a notifier for each configured channel, failing on
+// the first invalid one
+func NewDispatcher(configs map[string]ChannelConfig) (*Dispatcher, error) {
+	d := &Dispatcher{
+		channels: make(map[string]Notifier, len(configs)),
+		types:    make(map[string]string, len(configs)),
+	}
+	for name, cfg := range configs {
+		notifier, err := New(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("channel %s: %w", name, err)
+		}
+		d.channels[strings.ToLower(name)] = notifier
+		d.types[strings.ToLower(name)] = cfg.Type
+	}
+	return d, nil
+}
+
+// Has reports whether a channel is configured. Channel names are case
+// insensitive since the config file's keys are.
+func (d *Dispatcher) Has(name string) bool {
+	_, ok := d.channels[strings.ToLower(name)]
+	return ok
+}
+
+// Type returns the type of a configured channel
+func (d *Dispatcher) Type(name string) string {
+	return d.types[strings.ToLower(name)]
+}
+
+// Channels returns the names of the configured channels
+func (d *Dispatcher) Channels() []string {
+	names := make([]string, 0, len(d.channels))
+	for name := range d.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// statusKey is the context key send stores the last HTTP status under
+type statusKey struct{}
+
+// Deliver sends an event to the named channel, returning the HTTP status
+// of the last request made for channels that use HTTP
+func (d *Dispatcher) Deliver(channel string, event Event) (int, error) {
+	return d.call(channel, event, Notifier.Notify)
+}
+
+// Resolve resolves the alert an event sent to the named channel, for
+// channels that are Resolvers. Others have nothing to resolve.
+func (d *Dispatcher) Resolve(channel string, event Event) (int, error) {
+	return d.call(channel, event, func(notifier Notifier, ctx context.Context, event Event) error {
+		if resolver, ok := notifier.(Resolver); ok {
+			return resolver.Resolve(ctx, event)
+		}
+		return nil
+	})
+}
+
+// call runs fn against the named channel with the event's routing key for
+// it, returning the HTTP status of the last request made
+func (d *Dispatcher) call(channel string, event Event, fn func(Notifier, context.Context, Event) error) (int, error) {
+	notifier, ok := d.channels[strings.ToLower(channel)]
+	if !ok {
+		return 0, fmt.Errorf("unknown channel %s", channel)
+	}
+
+	event.RoutingKey = ""
+	for name, key := range event.RoutingKeys {
+		if strings.EqualFold(name, channel) {
+			event.RoutingKey = key
+		}
+	}
+
+	var status int
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), statusKey{}, &status), sendTimeout)
+	defer cancel()
+
+	err := fn(notifier, ctx, event)
+	return status, err
+}
@@ -0,0 +1,266 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// Default incident management API endpoints
+const (
+	defaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+	defaultOpsgenieURL  = "https://api.opsgenie.com"
+)
+
+// defaultIncidentTemplate is the alert title for PagerDuty and Opsgenie
+const defaultIncidentTemplate = `{{.Message}}`
+
+var severities = []string{types.SeverityCritical, types.SeverityError, types.SeverityWarning, types.SeverityInfo}
+
+// opsgeniePriorities maps severities to Opsgenie's alert priorities
+var opsgeniePriorities = map[string]string{
+	types.SeverityCritical: "P1",
+	types.SeverityError:    "P2",
+	types.SeverityWarning:  "P3",
+	types.SeverityInfo:     "P5",
+}
+
+// dedupKey identifies the alert an event triggers or resolves. Down and
+// recovery share one key per monitor so a recovery resolves the page,
+// certificate warnings and renewals share another and tests get their own.
+func dedupKey(event Event) string {
+	switch event.Type {
+	case types.EventCertExpiry, types.EventCertRenewed:
+		return fmt.Sprintf("go-up-%d-cert", event.MonitorID)
+	case types.EventTest:
+		return fmt.Sprintf("go-up-%d-test", event.MonitorID)
+	}
+	return fmt.Sprintf("go-up-%d", event.MonitorID)
+}
+
+// resolves reports whether an event resolves the alert for its dedup key
+// rather than triggering one
+func resolves(event Event) bool {
+	return event.Type == types.EventRecovery || event.Type == types.EventCertRenewed
+}
+
+// severity picks the event's severity from the monitor, then the channel.
+// Certificate warnings are always a warning.
+func severity(event Event, channelDefault string) string {
+	switch {
+	case event.Type == types.EventCertExpiry:
+		return types.SeverityWarning
+	case event.Severity != "":
+		return event.Severity
+	case channelDefault != "":
+		return channelDefault
+	default:
+		return types.SeverityCritical
+	}
+}
+
+// ValidateSeverity checks s is a known severity, or empty for the default
+func ValidateSeverity(s string) error {
+	if s != "" && !slices.Contains(severities, s) {
+		return fmt.Errorf("unknown severity %q, expected critical, error, warning or info", s)
+	}
+	return nil
+}
+
+// eventDetails are the event's fields shown on the alert
+func eventDetails(event Event) map[string]string {
+	details := map[string]string{
+		"monitor":       event.Monitor,
+		"url":           event.URL,
+		"status":        event.Status,
+		"response_time": fmt.Sprintf("%d ms", event.ResponseTime),
+		"uptime_24h":    fmt.Sprintf("%.2f%%", event.Uptime24Hours),
+		"uptime_30d":    fmt.Sprintf("%.2f%%", event.Uptime30Days),
+	}
+	if event.Error != "" {
+		details["error"] = event.Error
+		details["error_class"] = event.ErrorClass
+	}
+	if event.CertExpiry != nil {
+		details["cert_expiry"] = event.CertExpiry.Format(time.RFC3339)
+	}
+	if event.IncidentID != 0 {
+		details["incident_id"] = strconv.Itoa(event.IncidentID)
+	}
+	return details
+}
+
+// pagerDuty sends events to the PagerDuty Events API v2, triggering an
+// alert when a monitor goes down and resolving it on recovery
+type pagerDuty struct {
+	url        string
+	routingKey string
+	severity   string
+	summary    *template.Template
+	client     *http.Client
+}
+
+func newPagerDuty(cfg ChannelConfig) (Notifier, error) {
+	if cfg.RoutingKey == "" {
+		return nil, fmt.Errorf("pagerduty channel needs a routing key")
+	}
+	if err := ValidateSeverity(cfg.Severity); err != nil {
+		return nil, err
+	}
+
+	p := &pagerDuty{
+		url:        cfg.URL,
+		routingKey: cfg.RoutingKey,
+		severity:   cfg.Severity,
+		client:     &http.Client{},
+	}
+	if p.url == "" {
+		p.url = defaultPagerDutyURL
+	}
+	if err := validateURL(p.url); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if p.summary, err = parseTemplate("summary", cfg.Template, defaultIncidentTemplate); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// key returns the routing key to use for an event
+func (p *pagerDuty) key(event Event) string {
+	if event.RoutingKey != "" {
+		return event.RoutingKey
+	}
+	return p.routingKey
+}
+
+func (p *pagerDuty) Notify(ctx context.Context, event Event) error {
+	if resolves(event) {
+		return p.Resolve(ctx, event)
+	}
+
+	summary, err := render(p.summary, event)
+	if err != nil {
+		return fmt.Errorf("rendering summary template: %w", err)
+	}
+
+	body := map[string]any{
+		"routing_key":  p.key(event),
+		"dedup_key":    dedupKey(event),
+		"event_action": "trigger",
+		"client":       "go-up",
+		"payload": map[string]any{
+			"summary":        truncate(summary, 1024),
+			"source":         event.URL,
+			"severity":       severity(event, p.severity),
+			"timestamp":      event.Time.UTC().Format(time.RFC3339),
+			"component":      event.Monitor,
+			"class":          event.ErrorClass,
+			"custom_details": eventDetails(event),
+		},
+	}
+	if href := link(event); href != "" {
+		body["links"] = []map[string]string{{"href": href, "text": event.Monitor}}
+	}
+	return postJSON(ctx, p.client, p.url, body, nil)
+}
+
+// Resolve resolves the alert the event triggered
+func (p *pagerDuty) Resolve(ctx context.Context, event Event) error {
+	return postJSON(ctx, p.client, p.url, map[string]any{
+		"routing_key":  p.key(event),
+		"dedup_key":    dedupKey(event),
+		"event_action": "resolve",
+	}, nil)
+}
+
+// opsgenie creates Opsgenie alerts when a monitor goes down and closes them
+// on recovery, using the dedup key as the alert's alias
+type opsgenie struct {
+	url      string
+	apiKey   string
+	severity string
+	message  *template.Template
+	client   *http.Client
+}
+
+func newOpsgenie(cfg ChannelConfig) (Notifier, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("opsgenie channel needs an API key")
+	}
+	if err := ValidateSeverity(cfg.Severity); err != nil {
+		return nil, err
+	}
+
+	o := &opsgenie{
+		url:      cfg.URL,
+		apiKey:   cfg.APIKey,
+		severity: cfg.Severity,
+		client:   &http.Client{},
+	}
+	if o.url == "" {
+		o.url = defaultOpsgenieURL
+	}
+	if err := validateURL(o.url); err != nil {
+		return nil, err
+	}
+	o.url = strings.TrimRight(o.url, "/")
+
+	var err error
+	if o.message, err = parseTemplate("message", cfg.Template, defaultIncidentTemplate); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// key returns the API key to use for an event
+func (o *opsgenie) key(event Event) string {
+	if event.RoutingKey != "" {
+		return event.RoutingKey
+	}
+	return o.apiKey
+}
+
+func (o *opsgenie) Notify(ctx context.Context, event Event) error {
+	if resolves(event) {
+		return o.Resolve(ctx, event)
+	}
+
+	message, err := render(o.message, event)
+	if err != nil {
+		return fmt.Errorf("rendering message template: %w", err)
+	}
+	return postJSON(ctx, o.client, o.url+"/v2/alerts", map[string]any{
+		"message":     truncate(message, 130),
+		"alias":       dedupKey(event),
+		"description": event.Error,
+		"priority":    opsgeniePriorities[severity(event, o.severity)],
+		"source":      "go-up",
+		"entity":      event.Monitor,
+		"details":     eventDetails(event),
+		"tags":        []string{"go-up", event.Type},
+	}, map[string]string{
+		"Authorization": "GenieKey " + o.key(event),
+	})
+}
+
+// Resolve closes the alert the event created
+func (o *opsgenie) Resolve(ctx context.Context, event Event) error {
+	endpoint := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", o.url, url.PathEscape(dedupKey(event)))
+	return postJSON(ctx, o.client, endpoint, map[string]any{
+		"source": "go-up",
+		"note":   event.Message,
+	}, map[string]string{
+		"Authorization": "GenieKey " + o.key(event),
+	})
+}
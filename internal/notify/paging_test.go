@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// request is a request received by a recorder
+type request struct {
+	Path string
+	Body map[string]any
+}
+
+// recorder is a stand-in API that records the requests it receives
+type recorder struct {
+	mu       sync.Mutex
+	requests []request
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var decoded map[string]any
+	json.Unmarshal(body, &decoded)
+
+	rec.mu.Lock()
+	rec.requests = append(rec.requests, request{Path: r.URL.RequestURI(), Body: decoded})
+	rec.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func certEvents() []Event {
+	expiry := time.Now().Add(3 * 24 * time.Hour)
+	return []Event{
+		{Type: types.EventCertExpiry, MonitorID: 7, Monitor: "api", Status: types.StatusUp, Message: "api: certificate expires in 3 days", CertExpiry: &expiry},
+		{Type: types.EventCertRenewed, MonitorID: 7, Monitor: "api", Status: types.StatusUp, Message: "api: certificate renewed"},
+	}
+}
+
+func TestPagerDutyResolvesRenewedCertificate(t *testing.T) {
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	notifier, err := newPagerDuty(ChannelConfig{Type: "pagerduty", RoutingKey: "key", URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range certEvents() {
+		if err := notifier.Notify(context.Background(), event); err != nil {
+			t.Fatalf("%s: %v", event.Type, err)
+		}
+	}
+
+	if len(rec.requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(rec.requests))
+	}
+	for i, action := range []string{"trigger", "resolve"} {
+		body := rec.requests[i].Body
+		if body["event_action"] != action || body["dedup_key"] != "go-up-7-cert" {
+			t.Errorf("request %d: got %v %v, want %s go-up-7-cert", i, body["event_action"], body["dedup_key"], action)
+		}
+	}
+}
+
+func TestOpsgenieClosesRenewedCertificate(t *testing.T) {
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	notifier, err := newOpsgenie(ChannelConfig{Type: "opsgenie", APIKey: "key", URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range certEvents() {
+		if err := notifier.Notify(context.Background(), event); err != nil {
+			t.Fatalf("%s: %v", event.Type, err)
+		}
+	}
+
+	if len(rec.requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(rec.requests))
+	}
+	if alias := rec.requests[0].Body["alias"]; alias != "go-up-7-cert" {
+		t.Errorf("created alert with alias %v, want go-up-7-cert", alias)
+	}
+	if path := rec.requests[1].Path; path != "/v2/alerts/go-up-7-cert/close?identifierType=alias" {
+		t.Errorf("second request to %s, want the alert's close endpoint", path)
+	}
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/watzon/go-up/internal/types"
+)
+
+// templateFuncs are available in all message templates. json renders a
+// value as JSON, so strings can be embedded in a JSON body safely.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"emoji": statusEmoji,
+	"link":  link,
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.2f%%", f)
+	},
+}
+
+// executor is implemented by both text and HTML templates
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// parseTemplate parses a channel's text template, or def when none is
+// configured, and checks it renders for a sample event so mistakes like
+// unknown fields are caught when the channel is loaded
+func parseTemplate(name, text, def string) (*template.Template, error) {
+	if text == "" {
+		text = def
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s template: %w", name, err)
+	}
+	if _, err := render(tmpl, SampleEvent()); err != nil {
+		return nil, fmt.Errorf("checking %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// parseHTMLTemplate is parseTemplate for templates rendered as HTML, which
+// escape the values inserted into them
+func parseHTMLTemplate(name, text, def string) (*htmltemplate.Template, error) {
+	if text == "" {
+		text = def
+	}
+	tmpl, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s template: %w", name, err)
+	}
+	if _, err := render(tmpl, SampleEvent()); err != nil {
+		return nil, fmt.Errorf("checking %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func render(tmpl executor, event Event) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, event); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// SampleEvent returns a down event for a made up monitor, used to check
+// templates render
+func SampleEvent() Event {
+	now := time.Now()
+	started := now.Add(-5 * time.Minute)
+	expiry := now.Add(10 * 24 * time.Hour)
+	monitor := types.Monitor{
+		ID:            1,
+		Name:          "example",
+		URL:           "https://example.com",
+		Type:          types.MonitorTypeHTTP,
+		Interval:      time.Minute,
+		AlertChannels: []string{"example"},
+		IsActive:      true,
+	}
+
+	return Event{
+		ID:               "0123456789abcdef0123456789abcdef",
+		Type:             types.EventDown,
+		MonitorID:        monitor.ID,
+		Monitor:          monitor.Name,
+		URL:              monitor.URL,
+		Status:           types.StatusDown,
+		PreviousStatus:   types.StatusUp,
+		Message:          "example is down: unexpected status 503",
+		Error:            "unexpected status 503",
+		ErrorClass:       types.ErrorClassHTTPStatus,
+		ResponseTime:     120,
+		CertExpiry:       &expiry,
+		IncidentID:       1,
+		Uptime24Hours:    99.5,
+		Uptime30Days:     99.9,
+		Severity:         types.SeverityCritical,
+		IncidentDuration: now.Sub(started),
+		LastError:        "unexpected status 503",
+		Time:             now,
+		MonitorConfig:    monitor,
+		ServiceStatus: types.ServiceStatus{
+			ServiceURL:     monitor.URL,
+			ServiceName:    monitor.Name,
+			ResponseTime:   120,
+			Uptime24Hours:  99.5,
+			Uptime30Days:   99.9,
+			Status:         types.StatusDown,
+			LastError:      "unexpected status 503",
+			LastErrorClass: types.ErrorClassHTTPStatus,
+			StatusCode:     503,
+			IsActive:       true,
+		},
+		Recent: []types.HistoricalStat{
+			{Timestamp: now, Status: types.StatusDown, ResponseTime: 120, Error: "unexpected status 503"},
+			{Timestamp: started, Status: types.StatusUp, ResponseTime: 95},
+		},
+	}
+}
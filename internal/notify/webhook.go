@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	signing "github.com/watzon/go-up/pkg/webhook"
+)
+
+// maxErrorBodySize caps how much of a failed response is kept in the error
+const maxErrorBodySize = 512
+
+// webhook sends events as an HTTP request, with the event encoded as JSON
+// unless a body template is configured
+type webhook struct {
+	url     string
+	method  string
+	headers map[string]string
+	body    *template.Template
+	secret  string
+	client  *http.Client
+}
+
+func newWebhook(cfg ChannelConfig) (Notifier, error) {
+	if err := validateURL(cfg.URL); err != nil {
+		return nil, err
+	}
+
+	w := &webhook{
+		url:     cfg.URL,
+		method:  strings.ToUpper(cfg.Method),
+		headers: cfg.Headers,
+		secret:  cfg.Secret,
+		client:  &http.Client{},
+	}
+	if w.method == "" {
+		w.method = http.MethodPost
+	}
+
+	// The event is sent as JSON unless a body template is configured
+	body := cfg.Body
+	if body == "" {
+		body = cfg.Template
+	}
+	if body != "" {
+		tmpl, err := parseTemplate("body", body, "")
+		if err != nil {
+			return nil, err
+		}
+		w.body = tmpl
+	}
+
+	return w, nil
+}
+
+func (w *webhook) Notify(ctx context.Context, event Event) error {
+	var body bytes.Buffer
+	if w.body != nil {
+		if err := w.body.Execute(&body, event); err != nil {
+			return fmt.Errorf("rendering body template: %w", err)
+		}
+	} else if err := json.NewEncoder(&body).Encode(event); err != nil {
+		return err
+	}
+
+	payload := body.Bytes()
+	req, err := http.NewRequestWithContext(ctx, w.method, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-up")
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+	// Each attempt is signed when it's sent so retries aren't rejected as
+	// replays
+	if w.secret != "" {
+		signing.SetHeaders(req.Header, w.secret, event.ID, time.Now(), payload)
+	}
+
+	return send(w.client, req)
+}
+
+// send performs a request, treating any non-2xx response as an error
+func send(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if status, ok := req.Context().Value(statusKey{}).(*int); ok {
+		*status = resp.StatusCode
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		if body := strings.TrimSpace(string(b)); body != "" {
+			return fmt.Errorf("unexpected status %s: %s", resp.Status, body)
+		}
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// postJSON sends v as a JSON POST request with any extra headers
+func postJSON(ctx context.Context, client *http.Client, endpoint string, v any, headers map[string]string) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-up")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return send(client, req)
+}
+
+func validateURL(endpoint string) error {
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return fmt.Errorf("URL must be http or https, got %q", endpoint)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"log"
+	"os/user"
 	"time"
 
 	"github.com/gizak/termui/v3"
@@ -10,9 +11,13 @@ import (
 	"github.com/watzon/go-up/internal/types"
 )
 
+// maxIncidents is how many recent incidents the incident log shows
+const maxIncidents = 50
+
 type App struct {
 	client           *RPCClient
 	serviceList      *widgets.ServiceList
+	incidents        *widgets.IncidentLog
 	details          *widgets.DetailsPanel
 	debug            *widgets.DebugView
 	help             *widgets.HelpBar
@@ -33,6 +38,7 @@ func NewApp(debugMode bool, daemonAddr string) (*App, error) {
 	app := &App{
 		client:           client,
 		serviceList:      widgets.NewServiceList(),
+		incidents:        widgets.NewIncidentLog(),
 		details:          widgets.NewDetailsPanel(),
 		help:             widgets.NewHelpBar(),
 		currentMonitorID: -1, // Initialize to invalid ID
@@ -71,15 +77,26 @@ func (app *App) Run() error {
 			case "j", "<Down>":
 				if app.serviceList.GetSelectedIndex() < len(app.monitors) {
 					app.serviceList.MoveSelection(1, len(app.monitors))
+					app.details.ClearSelection()
 					app.updateSelectedMonitor(app.serviceList.GetSelectedIndex())
 					app.render()
 				}
 			case "k", "<Up>":
 				if app.serviceList.GetSelectedIndex() > 0 {
 					app.serviceList.MoveSelection(-1, len(app.monitors))
+					app.details.ClearSelection()
 					app.updateSelectedMonitor(app.serviceList.GetSelectedIndex())
 					app.render()
 				}
+			case "h", "<Left>":
+				app.details.SelectCheck(-1)
+				app.render()
+			case "l", "<Right>":
+				app.details.SelectCheck(1)
+				app.render()
+			case "c":
+				app.details.ClearSelection()
+				app.render()
 			case "<Resize>":
 				payload := e.Payload.(termui.Resize)
 				app.resize(payload.Width, payload.Height)
@@ -87,6 +104,12 @@ func (app *App) Run() error {
 			case "p":
 				app.handlePauseToggle()
 				app.render()
+			case "a":
+				app.handleAcknowledge()
+				if err := app.refreshData(); err != nil {
+					log.Printf("Error refreshing data: %v", err)
+				}
+				app.render()
 			}
 		case <-ticker.C:
 			if err := app.refreshData(); err != nil {
@@ -102,7 +125,10 @@ func (app *App) resize(width, height int) int {
 	rightPanelStart := leftPanelWidth + 1
 	rightPanelWidth := width - rightPanelStart - 1 // Subtract 1 for right border
 
-	app.serviceList.SetRect(0, 0, leftPanelWidth, height-3)
+	// Incident log below the monitor list, taking up to a third of the height
+	incidentsHeight := (height - 3) / 3
+	app.serviceList.SetRect(0, 0, leftPanelWidth, height-3-incidentsHeight)
+	app.incidents.SetRect(0, height-3-incidentsHeight, leftPanelWidth, height-3)
 
 	var chartWidth int
 	if app.debug != nil {
@@ -137,8 +163,12 @@ func (app *App) render() {
 
 	// Draw the frame
 	termui.Render(app.serviceList)
+	termui.Render(app.incidents)
 	termui.Render(app.details.Container) // Render container first
 	termui.Render(app.details.URLView)   // Then child components
+	termui.Render(app.details.ErrorView)
+	termui.Render(app.details.CheckView)
+	termui.Render(app.details.Timings)
 	termui.Render(app.details.Chart)
 	termui.Render(app.details.Stats)
 	termui.Render(app.help)
@@ -243,6 +273,25 @@ func (app *App) handlePauseToggle() {
 	app.serviceList.TogglePause(monitor.Name)
 }
 
+// handleAcknowledge acknowledges the selected monitor's ongoing incident
+func (app *App) handleAcknowledge() {
+	selectedIdx := app.serviceList.GetSelectedIndex()
+	if selectedIdx >= len(app.monitors) {
+		return
+	}
+
+	by := "unknown"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		by = u.Username
+	}
+
+	monitor := app.monitors[selectedIdx]
+	err := app.client.acknowledgeAlert(monitor.Name, by)
+	if err != nil && app.debug != nil {
+		app.debug.Printf("Error acknowledging %s: %v", monitor.Name, err)
+	}
+}
+
 func (app *App) refreshData() error {
 	// Fetch latest monitors
 	monitors, err := app.client.listMonitors()
@@ -267,6 +316,15 @@ func (app *App) refreshData() error {
 	// Update service list
 	app.serviceList.Update(monitors, currentStatus)
 
+	incidents, err := app.client.listIncidents(maxIncidents)
+	if err != nil {
+		if app.debug != nil {
+			app.debug.Printf("Error fetching incidents: %v", err)
+		}
+	} else {
+		app.incidents.Update(incidents)
+	}
+
 	// Update details panel for selected service
 	selectedIdx := app.serviceList.GetSelectedIndex()
 	if selectedIdx < len(monitors) {
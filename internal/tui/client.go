@@ -98,6 +98,18 @@ func (c *RPCClient) resumeMonitor(name string) error {
 	return c.call("Service.ResumeMonitor", name, &reply)
 }
 
+func (c *RPCClient) acknowledgeAlert(name, by string) error {
+	var reply string
+	args := struct {
+		MonitorName string
+		By          string
+	}{
+		MonitorName: name,
+		By:          by,
+	}
+	return c.call("Service.AcknowledgeAlert", args, &reply)
+}
+
 func (c *RPCClient) getHistoricalStats(monitorID int, count int, debug *widgets.DebugView) ([]types.HistoricalStat, error) {
 	var stats []types.HistoricalStat
 	args := struct {
@@ -113,3 +125,16 @@ func (c *RPCClient) getHistoricalStats(monitorID int, count int, debug *widgets.
 	}
 	return stats, err
 }
+
+func (c *RPCClient) listIncidents(count int) ([]types.Incident, error) {
+	var incidents []types.Incident
+	args := struct {
+		MonitorName string
+		Count       int
+		OpenOnly    bool
+	}{
+		Count: count,
+	}
+	err := c.call("Service.ListIncidents", args, &incidents)
+	return incidents, err
+}
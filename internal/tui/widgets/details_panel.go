@@ -1,7 +1,9 @@
 package widgets
 
 import (
+	"fmt"
 	"image"
+	"strings"
 	"sync"
 
 	"github.com/gizak/termui/v3"
@@ -12,9 +14,14 @@ import (
 type DetailsPanel struct {
 	Container *termui.Block
 	URLView   *widgets.Paragraph
+	ErrorView *widgets.Paragraph
+	CheckView *widgets.Paragraph
+	Timings   *TimingBar
 	Chart     *ResponseChart
 	Stats     *StatsTable
 	sync.Mutex
+
+	avgTimings types.Timings
 }
 
 func NewDetailsPanel() *DetailsPanel {
@@ -27,9 +34,20 @@ func NewDetailsPanel() *DetailsPanel {
 	urlView.Border = false
 	urlView.TextStyle.Modifier = termui.ModifierBold | termui.ModifierUnderline
 
+	errorView := widgets.NewParagraph()
+	errorView.TextStyle = termui.NewStyle(termui.ColorRed)
+	errorView.Border = false
+
+	checkView := widgets.NewParagraph()
+	checkView.TextStyle = termui.NewStyle(termui.ColorWhite)
+	checkView.Border = false
+
 	return &DetailsPanel{
 		Container: container,
 		URLView:   urlView,
+		ErrorView: errorView,
+		CheckView: checkView,
+		Timings:   NewTimingBar(),
 		Chart:     NewResponseChart(),
 		Stats:     NewStatsTable(),
 	}
@@ -45,14 +63,26 @@ func (d *DetailsPanel) SetRect(x1, y1, x2, y2 int) {
 	urlStart := y1 + 1
 	d.URLView.SetRect(x1+1, urlStart, x2-1, urlStart+1)
 
+	// Last error, if any, directly below the URL
+	errorStart := urlStart + 1
+	d.ErrorView.SetRect(x1+1, errorStart, x2-1, errorStart+1)
+
 	// Stats at the bottom, 7 rows high
 	statsHeight := 7
 	statsStart := y2 - statsHeight - 1 // -1 for container border
 	d.Stats.SetRect(x1+1, statsStart, x2-1, statsStart+statsHeight)
 
-	// Chart fills remaining space between URL and stats
-	chartStart := urlStart + 1
-	chartHeight := statsStart - chartStart
+	// Details and timing breakdown of the selected check above the stats
+	checkStart := statsStart - 1
+	d.CheckView.SetRect(x1+1, checkStart, x2-1, checkStart+1)
+
+	timingsHeight := 3
+	timingsStart := checkStart - timingsHeight
+	d.Timings.SetRect(x1+1, timingsStart, x2-1, timingsStart+timingsHeight)
+
+	// Chart fills remaining space between error line and timings
+	chartStart := errorStart + 1
+	chartHeight := timingsStart - chartStart
 	d.Chart.SetRect(x1+1, chartStart, x2-1, chartStart+chartHeight)
 
 	// Initialize chart with stored stats if we have them
@@ -64,9 +94,23 @@ func (d *DetailsPanel) Update(status types.ServiceStatus) {
 	defer d.Unlock()
 
 	d.Container.Title = status.ServiceName
+	if status.Maintenance != "" {
+		d.Container.Title += fmt.Sprintf(" (maintenance: %s)", status.Maintenance)
+	}
 	d.URLView.Text = status.ServiceURL
+	d.ErrorView.Text = status.LastError
+	d.ErrorView.TextStyle = termui.NewStyle(termui.ColorRed)
+	if status.LastError == "" && status.LastWarning != "" {
+		d.ErrorView.Text = status.LastWarning
+		d.ErrorView.TextStyle = termui.NewStyle(termui.ColorYellow)
+	} else if status.LastError == "" && status.LastAnswer != "" {
+		d.ErrorView.Text = "Answer: " + status.LastAnswer
+		d.ErrorView.TextStyle = termui.NewStyle(termui.ColorWhite)
+	}
 	d.Chart.Update(status)
 	d.Stats.Update(status)
+	d.avgTimings = status.AvgTimings
+	d.updateCheckView()
 }
 
 func (d *DetailsPanel) Draw(buf *termui.Buffer) {
@@ -75,6 +119,9 @@ func (d *DetailsPanel) Draw(buf *termui.Buffer) {
 
 	d.Container.Draw(buf)
 	d.URLView.Draw(buf)
+	d.ErrorView.Draw(buf)
+	d.CheckView.Draw(buf)
+	d.Timings.Draw(buf)
 	d.Chart.Draw(buf)
 	d.Stats.Draw(buf)
 }
@@ -114,4 +161,65 @@ func (d *DetailsPanel) InitializeChart(stats []types.HistoricalStat, debug *Debu
 		}
 		d.Chart.StoreHistoricalStats(stats)
 	}
+	d.updateCheckView()
+}
+
+// SelectCheck moves the chart selection by delta and shows the details of
+// the selected check
+func (d *DetailsPanel) SelectCheck(delta int) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.Chart.MoveSelection(delta)
+	d.updateCheckView()
+}
+
+func (d *DetailsPanel) ClearSelection() {
+	d.Lock()
+	defer d.Unlock()
+
+	d.Chart.ClearSelection()
+	d.updateCheckView()
+}
+
+func (d *DetailsPanel) updateCheckView() {
+	stat, ok := d.Chart.SelectedStat()
+	if !ok {
+		d.CheckView.Text = ""
+		d.Timings.Update(types.Timings{}, d.avgTimings)
+		return
+	}
+	d.CheckView.Text = formatCheck(stat)
+	d.Timings.Update(stat.Timings, d.avgTimings)
+}
+
+func formatCheck(stat types.HistoricalStat) string {
+	status := strings.ToUpper(stat.Status)
+	if stat.IsRetry {
+		status = fmt.Sprintf("RETRY %d", stat.Attempt)
+	}
+
+	parts := []string{
+		stat.Timestamp.Local().Format("2006-01-02 15:04:05"),
+		status,
+		fmt.Sprintf("%d ms", stat.ResponseTime),
+	}
+	if stat.StatusCode != 0 {
+		parts = append(parts, fmt.Sprintf("%d %s", stat.StatusCode, stat.Protocol))
+	}
+	if stat.ResponseSize > 0 {
+		parts = append(parts, fmt.Sprintf("%d B", stat.ResponseSize))
+	}
+	if stat.Answer != "" {
+		parts = append(parts, stat.Answer)
+	}
+	if stat.Error != "" {
+		parts = append(parts, fmt.Sprintf("[%s] %s", stat.ErrorClass, stat.Error))
+	} else if stat.Warning != "" {
+		parts = append(parts, stat.Warning)
+	} else if stat.Message != "" {
+		parts = append(parts, stat.Message)
+	}
+
+	return strings.Join(parts, " | ")
 }
@@ -19,7 +19,7 @@ func NewHelpBar() *HelpBar {
 }
 
 func (h *HelpBar) UpdateHelp(hasDebug bool, isPaused bool) {
-	baseHelp := "q: Quit | ↑/k: Up | ↓/j: Down"
+	baseHelp := "q: Quit | ↑/k: Up | ↓/j: Down | ←/h →/l: Select Check | c: Clear Selection"
 	debugHelp := ""
 	pauseHelp := ""
 
@@ -33,5 +33,5 @@ func (h *HelpBar) UpdateHelp(hasDebug bool, isPaused bool) {
 		pauseHelp = " | p: Pause Monitor"
 	}
 
-	h.Text = baseHelp + pauseHelp + debugHelp
+	h.Text = baseHelp + pauseHelp + " | a: Acknowledge" + debugHelp
 }
@@ -0,0 +1,47 @@
+package widgets
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/watzon/go-up/internal/types"
+)
+
+// IncidentLog lists recent incidents across all monitors, ongoing ones in red
+// or yellow once acknowledged
+type IncidentLog struct {
+	*widgets.List
+}
+
+func NewIncidentLog() *IncidentLog {
+	list := widgets.NewList()
+	list.Title = "Incidents"
+	list.TextStyle = termui.NewStyle(termui.ColorWhite)
+	list.WrapText = false
+
+	return &IncidentLog{List: list}
+}
+
+func (l *IncidentLog) Update(incidents []types.Incident) {
+	if len(incidents) == 0 {
+		l.Rows = []string{"No incidents"}
+		return
+	}
+
+	rows := make([]string, len(incidents))
+	for i, incident := range incidents {
+		started := incident.StartedAt.Local().Format("01-02 15:04")
+		duration := incident.Duration.Round(time.Second)
+		switch {
+		case incident.EndedAt.IsZero() && !incident.AcknowledgedAt.IsZero():
+			rows[i] = fmt.Sprintf("[● %s %s %s ack](fg:yellow)", incident.MonitorName, started, duration)
+		case incident.EndedAt.IsZero():
+			rows[i] = fmt.Sprintf("[● %s %s %s](fg:red)", incident.MonitorName, started, duration)
+		default:
+			rows[i] = fmt.Sprintf("[●](fg:green) %s %s %s", incident.MonitorName, started, duration)
+		}
+	}
+	l.Rows = rows
+}
@@ -1,6 +1,8 @@
 package widgets
 
 import (
+	"time"
+
 	"github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
 	"github.com/watzon/go-up/internal/types"
@@ -10,8 +12,11 @@ type ResponseChart struct {
 	*widgets.BarChart
 	data        []float64
 	labels      []string
-	statuses    []bool
+	statuses    []string
 	storedStats []types.HistoricalStat
+	history     []types.HistoricalStat
+	selected    int
+	selectedAt  time.Time
 	debug       *DebugView
 }
 
@@ -27,7 +32,8 @@ func NewResponseChart() *ResponseChart {
 		BarChart: chart,
 		data:     make([]float64, 0),
 		labels:   make([]string, 0),
-		statuses: make([]bool, 0),
+		statuses: make([]string, 0),
+		selected: -1,
 	}
 }
 
@@ -40,7 +46,7 @@ func (c *ResponseChart) Update(status types.ServiceStatus) {
 
 	c.data = append(c.data, responseValue)
 	c.labels = append(c.labels, "")
-	c.statuses = append(c.statuses, status.CurrentStatus)
+	c.statuses = append(c.statuses, status.Status)
 
 	// Calculate max bars that can fit in current width
 	maxBars := c.GetRect().Dx() / (c.BarWidth + 1)
@@ -55,7 +61,7 @@ func (c *ResponseChart) Update(status types.ServiceStatus) {
 	// Find max response time across all data points
 	maxResponseTime := 0.0
 	for i, v := range c.data {
-		if c.statuses[i] && v > maxResponseTime {
+		if c.statuses[i] != types.StatusDown && v > maxResponseTime {
 			maxResponseTime = v
 		}
 	}
@@ -79,11 +85,13 @@ func (c *ResponseChart) Update(status types.ServiceStatus) {
 	// Update colors based on status and clamp values to 999
 	c.BarColors = make([]termui.Color, len(c.data))
 	for i := range c.data {
-		if !c.statuses[i] {
+		if c.statuses[i] == types.StatusDown {
 			c.BarColors[i] = termui.ColorRed
 		} else if c.data[i] > 999 {
 			c.data[i] = 999
 			c.BarColors[i] = termui.ColorRed
+		} else if c.statuses[i] == types.StatusDegraded {
+			c.BarColors[i] = termui.ColorYellow
 		} else {
 			c.BarColors[i] = termui.ColorGreen
 		}
@@ -129,7 +137,7 @@ func (c *ResponseChart) InitializeHistory(stats []types.HistoricalStat, containe
 	// Clear existing data
 	c.data = make([]float64, 0, len(stats))
 	c.labels = make([]string, 0, len(stats))
-	c.statuses = make([]bool, 0, len(stats))
+	c.statuses = make([]string, 0, len(stats))
 
 	// Stats already come in reverse chronological order (newest first)
 	maxResponseTime := 0.0
@@ -150,7 +158,7 @@ func (c *ResponseChart) InitializeHistory(stats []types.HistoricalStat, containe
 
 		c.data = append(c.data, responseValue)
 		c.labels = append(c.labels, "")
-		c.statuses = append(c.statuses, stat.IsUp)
+		c.statuses = append(c.statuses, stat.Status)
 	}
 
 	// Set chart properties
@@ -166,15 +174,19 @@ func (c *ResponseChart) InitializeHistory(stats []types.HistoricalStat, containe
 		}
 	}
 
-	// Set colors based on status
-	c.BarColors = make([]termui.Color, len(c.data))
-	for i, isUp := range c.statuses {
-		if isUp {
-			c.BarColors[i] = termui.ColorGreen
-		} else {
-			c.BarColors[i] = termui.ColorRed
+	// Keep the selected check selected if it is still on the chart
+	c.history = stats
+	c.selected = -1
+	for i, stat := range stats {
+		if !c.selectedAt.IsZero() && stat.Timestamp.Equal(c.selectedAt) {
+			c.selected = i
 		}
 	}
+	if c.selected < 0 {
+		c.selectedAt = time.Time{}
+	}
+
+	c.applyColors()
 
 	// Set the data directly on the BarChart
 	c.Data = c.data
@@ -192,3 +204,61 @@ func (c *ResponseChart) InitializeHistory(stats []types.HistoricalStat, containe
 		}
 	}
 }
+
+// applyColors colors each bar by status and highlights the selected one
+func (c *ResponseChart) applyColors() {
+	c.BarColors = make([]termui.Color, len(c.data))
+	for i, status := range c.statuses {
+		switch {
+		case i == c.selected:
+			c.BarColors[i] = termui.ColorCyan
+		case status == types.StatusUp:
+			c.BarColors[i] = termui.ColorGreen
+		case status == types.StatusDegraded:
+			c.BarColors[i] = termui.ColorYellow
+		default:
+			c.BarColors[i] = termui.ColorRed
+		}
+	}
+}
+
+// MoveSelection moves the selected bar by delta, starting from the newest
+// check when nothing is selected yet
+func (c *ResponseChart) MoveSelection(delta int) {
+	if len(c.history) == 0 {
+		return
+	}
+
+	index := c.selected + delta
+	if c.selected < 0 {
+		index = 0
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(c.history) {
+		index = len(c.history) - 1
+	}
+
+	c.selected = index
+	c.selectedAt = c.history[index].Timestamp
+	c.applyColors()
+}
+
+func (c *ResponseChart) ClearSelection() {
+	c.selected = -1
+	c.selectedAt = time.Time{}
+	c.applyColors()
+}
+
+// SelectedStat returns the selected check, or the newest one when nothing
+// is selected
+func (c *ResponseChart) SelectedStat() (types.HistoricalStat, bool) {
+	if len(c.history) == 0 {
+		return types.HistoricalStat{}, false
+	}
+	if c.selected >= 0 && c.selected < len(c.history) {
+		return c.history[c.selected], true
+	}
+	return c.history[0], true
+}
@@ -55,8 +55,14 @@ func (s *ServiceList) getStatusIcon(serviceName string, exists bool, status type
 	if s.pausedMonitors[serviceName] {
 		return "⏸️"
 	}
-	if status.ResponseTime > 0 {
+	if status.Maintenance != "" {
+		return "🔧"
+	}
+	switch status.Status {
+	case types.StatusUp:
 		return "🟢"
+	case types.StatusDegraded:
+		return "🟡"
 	}
 	return "🔴"
 }
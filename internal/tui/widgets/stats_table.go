@@ -2,6 +2,7 @@ package widgets
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
@@ -18,14 +19,14 @@ func NewStatsTable() *StatsTable {
 	table.TextStyle = termui.NewStyle(termui.ColorWhite)
 	table.RowSeparator = true
 	table.FillRow = true
-	table.ColumnWidths = []int{15, 15, 15, 15, 15}
+	table.ColumnWidths = []int{13, 13, 13, 13, 13, 13}
 	table.TextAlignment = termui.AlignCenter
 
 	// Initialize with headers
 	table.Rows = [][]string{
-		{"Response", "Avg. Response", "Uptime", "Uptime", "Cert Exp."},
-		{"(current)", "(24-hour)", "(24-hour)", "(30-day)", ""},
-		{"--", "--", "--", "--", "--"},
+		{"Response", "Avg. Response", "Uptime", "Uptime", "Degraded", "Cert Exp."},
+		{"(current)", "(24-hour)", "(24-hour)", "(30-day)", "(24-hour)", ""},
+		{"--", "--", "--", "--", "--", "--"},
 	}
 
 	return &StatsTable{Table: table}
@@ -33,14 +34,22 @@ func NewStatsTable() *StatsTable {
 
 func (s *StatsTable) Update(status types.ServiceStatus) {
 	s.Rows = [][]string{
-		{"Response", "Avg. Response", "Uptime", "Uptime", "Cert Exp."},
-		{"(current)", "(24-hour)", "(24-hour)", "(30-day)", ""},
+		{"Response", "Avg. Response", "Uptime", "Uptime", "Degraded", "Cert Exp."},
+		{"(current)", "(24-hour)", "(24-hour)", "(30-day)", "(24-hour)", ""},
 		{
 			fmt.Sprintf("%d ms", status.ResponseTime),
 			fmt.Sprintf("%d", int(status.AvgResponseTime)),
 			fmt.Sprintf("%d%%", int(status.Uptime24Hours)),
 			fmt.Sprintf("%d%%", int(status.Uptime30Days)),
-			status.CertificateExpiry.Format("2006-01-02"),
+			fmt.Sprintf("%d%%", int(status.Degraded24Hours)),
+			formatCertExpiry(status.CertificateExpiry),
 		},
 	}
 }
+
+func formatCertExpiry(expiry time.Time) string {
+	if expiry.IsZero() {
+		return "--"
+	}
+	return expiry.Format("2006-01-02")
+}
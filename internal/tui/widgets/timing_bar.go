@@ -0,0 +1,90 @@
+package widgets
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/watzon/go-up/internal/types"
+)
+
+type timingPhase struct {
+	name  string
+	color string
+	value time.Duration
+}
+
+// TimingBar shows the phases of a check as a stacked bar with a legend
+type TimingBar struct {
+	*widgets.Paragraph
+}
+
+func NewTimingBar() *TimingBar {
+	p := widgets.NewParagraph()
+	p.Border = false
+	p.TextStyle = termui.NewStyle(termui.ColorWhite)
+	p.WrapText = false
+	// Blocks reserve a cell for the border on every side even when it isn't
+	// drawn, so cancel that out to use all three rows
+	p.PaddingTop, p.PaddingBottom, p.PaddingLeft, p.PaddingRight = -1, -1, -1, -1
+
+	return &TimingBar{Paragraph: p}
+}
+
+func (t *TimingBar) Update(timings, avg types.Timings) {
+	phases := []timingPhase{
+		{"DNS", "blue", timings.DNS},
+		{"Connect", "magenta", timings.Connect},
+		{"TLS", "yellow", timings.TLS},
+		{"TTFB", "green", timings.TTFB},
+		{"Transfer", "cyan", timings.Transfer},
+	}
+
+	var total time.Duration
+	for _, phase := range phases {
+		total += phase.value
+	}
+	if total == 0 && avg == (types.Timings{}) {
+		t.Text = ""
+		return
+	}
+
+	width := t.Inner.Dx()
+	var bar strings.Builder
+	used := 0
+	for i, phase := range phases {
+		if total == 0 || phase.value == 0 {
+			continue
+		}
+		cells := int(float64(width) * float64(phase.value) / float64(total))
+		if i == len(phases)-1 || used+cells > width {
+			cells = width - used
+		}
+		if cells < 1 {
+			cells = 1
+		}
+		used += cells
+		fmt.Fprintf(&bar, "[%s](bg:%s)", strings.Repeat(" ", cells), phase.color)
+	}
+
+	legend := make([]string, len(phases))
+	for i, phase := range phases {
+		legend[i] = fmt.Sprintf("[%s](fg:%s) %s", phase.name, phase.color, formatPhase(phase.value))
+	}
+
+	averages := []time.Duration{avg.DNS, avg.Connect, avg.TLS, avg.TTFB, avg.Transfer}
+	avgLegend := make([]string, len(phases))
+	for i, phase := range phases {
+		avgLegend[i] = fmt.Sprintf("%s %s", phase.name, formatPhase(averages[i]))
+	}
+
+	t.Text = bar.String() + "\n" +
+		strings.Join(legend, "  ") + "\n" +
+		"avg: " + strings.Join(avgLegend, "  ")
+}
+
+func formatPhase(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
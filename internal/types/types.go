@@ -4,6 +4,100 @@ import (
 	"time"
 )
 
+// Monitor types understood by the daemon
+const (
+	MonitorTypeHTTP = "http"
+	MonitorTypeTCP  = "tcp"
+	MonitorTypeDNS  = "dns"
+	MonitorTypePush = "push"
+	MonitorTypeExec = "exec"
+	MonitorTypeGRPC = "grpc"
+)
+
+// Statuses a check can end in. Degraded checks count as up for uptime.
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+// Events monitors can send alerts for
+const (
+	EventDown       = "down"
+	EventRecovery   = "recovery"
+	EventCertExpiry = "cert_expiry"
+)
+
+// Alerts sent by alert policies while a monitor stays down, and the
+// acknowledgement that stops them. They are sent for monitors alerting on down.
+const (
+	EventReminder     = "reminder"
+	EventEscalation   = "escalation"
+	EventAcknowledged = "acknowledged"
+)
+
+// EventCertRenewed is sent for monitors alerting on cert_expiry once the
+// certificate they were alerted about no longer expires soon, resolving
+// the alert
+const EventCertRenewed = "cert_renewed"
+
+// EventTest is sent by go-up notify test to check a channel works
+const EventTest = "test"
+
+// Maintenance window modes. Checks stop during a pause, while silenced
+// monitors are still checked but the checks don't count towards uptime or
+// send alerts.
+const (
+	MaintenancePause   = "pause"
+	MaintenanceSilence = "silence"
+)
+
+// Alert severities, as used by incident management services
+const (
+	SeverityCritical = "critical"
+	SeverityError    = "error"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// Assertion types that can be run against a response body
+const (
+	AssertContains    = "contains"
+	AssertNotContains = "not_contains"
+	AssertRegex       = "regex"
+	AssertJSON        = "json"
+)
+
+// Operators supported by JSON assertions
+const (
+	JSONOpExists       = "exists"
+	JSONOpEquals       = "=="
+	JSONOpNotEquals    = "!="
+	JSONOpGreater      = ">"
+	JSONOpGreaterEqual = ">="
+	JSONOpLess         = "<"
+	JSONOpLessEqual    = "<="
+)
+
+// Error classes recorded with failed checks
+const (
+	ErrorClassDNS               = "dns"
+	ErrorClassTimeout           = "timeout"
+	ErrorClassConnectionRefused = "connection_refused"
+	ErrorClassConnectionReset   = "connection_reset"
+	ErrorClassConnection        = "connection"
+	ErrorClassTLS               = "tls"
+	ErrorClassCertificate       = "certificate"
+	ErrorClassHTTPStatus        = "http_status"
+	ErrorClassAssertion         = "assertion"
+	ErrorClassUnexpected        = "unexpected_response"
+	ErrorClassConfig            = "config"
+	ErrorClassMissedHeartbeat   = "missed_heartbeat"
+	ErrorClassJobFailed         = "job_failed"
+	ErrorClassCommand           = "command"
+	ErrorClassGRPCStatus        = "grpc_status"
+)
+
 // ServiceStatus represents the status of a monitored service
 type ServiceStatus struct {
 	ServiceURL        string
@@ -13,19 +107,259 @@ type ServiceStatus struct {
 	Uptime24Hours     float64
 	Uptime30Days      float64
 	CurrentStatus     bool
+	Status            string
+	Degraded24Hours   float64
+	Degraded30Days    float64
 	CertificateExpiry time.Time
+	CertificateIssuer string
+	CertificateSANs   []string
+	TLSError          string
+	LastError         string
+	LastErrorClass    string
+	LastWarning       string
+	LastMessage       string
+	LastAnswer        string
+	Metrics           []Metric
+	StatusCode        int
+	ResponseSize      int64
+	Protocol          string
+	Timings           Timings
+	AvgTimings        Timings
 	IsActive          bool
+	// Maintenance is the name of the maintenance window the monitor is in
+	Maintenance string
 }
 
 type Monitor struct {
-	ID       int
-	Name     string
-	URL      string
-	IsActive bool
+	ID                int
+	Name              string
+	URL               string
+	Type              string
+	Interval          time.Duration
+	Timeout           time.Duration
+	Retries           int
+	RetryInterval     time.Duration
+	Method            string
+	Headers           map[string]string
+	Body              string
+	AcceptedStatus    []string
+	NoFollowRedirects bool
+	TLSSkipVerify     bool
+	TLSCABundle       string
+	CertWarnDays      int
+	CertDownDays      int
+	TCPSend           string
+	TCPExpect         string
+	DNSRecordType     string
+	DNSResolver       string
+	DNSExpected       []string
+	GRPCService       string
+	GRPCTLS           bool
+	Assertions        []Assertion
+	MaxBodySize       int64
+	DegradedThreshold time.Duration
+	PushToken         string
+	PushGrace         time.Duration
+	AlertChannels     []string
+	AlertEvents       []string
+	AlertSeverity     string
+	AlertRoutingKeys  map[string]string
+	AlertDelay        time.Duration
+	AlertRepeat       time.Duration
+	EscalateAfter     time.Duration
+	EscalateChannels  []string
+	Tags              []string
+	IsActive          bool
+}
+
+// Assertion is a condition the response body must satisfy for the monitor
+// to be considered up. Path and Op are only used by JSON assertions. A
+// failing soft assertion only marks the monitor degraded.
+type Assertion struct {
+	Type  string
+	Path  string
+	Op    string
+	Value string
+	Soft  bool
 }
 
 type HistoricalStat struct {
 	ResponseTime int
 	IsUp         bool
+	Status       string
+	Answer       string
+	Error        string
+	ErrorClass   string
+	Warning      string
+	Message      string
+	Metrics      []Metric
+	StatusCode   int
+	ResponseSize int64
+	Protocol     string
+	Timings      Timings
+	Attempt      int
+	IsRetry      bool
 	Timestamp    time.Time
 }
+
+// CheckResult is the outcome of a single check run against a monitor
+type CheckResult struct {
+	ResponseTime time.Duration
+	IsUp         bool
+	Status       string
+	CertExpiry   time.Time
+	CertIssuer   string
+	CertSANs     []string
+	TLSError     string
+	Answer       string
+	Error        string
+	ErrorClass   string
+	Warning      string
+	Message      string
+	Metrics      []Metric
+	StatusCode   int
+	ResponseSize int64
+	Protocol     string
+	Timings      Timings
+	Attempt      int
+	IsRetry      bool
+	Maintenance  bool
+}
+
+// Timings breaks an HTTP check down into its phases. TTFB is measured from
+// the request being written to the first response byte, so the phases add
+// up to the total time of the request.
+type Timings struct {
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	TTFB     time.Duration
+	Transfer time.Duration
+}
+
+// Incident is a period during which a monitor was down. EndedAt is zero
+// while the incident is ongoing, and the alert times are zero until the
+// alert is sent. Checks is only filled in by GetIncident.
+type Incident struct {
+	ID             int
+	MonitorID      int
+	MonitorName    string
+	MonitorURL     string
+	StartedAt      time.Time
+	EndedAt        time.Time
+	Duration       time.Duration
+	FirstError     string
+	ErrorClass     string
+	LastError      string
+	CheckCount     int
+	AlertedAt      time.Time
+	LastAlertAt    time.Time
+	EscalatedAt    time.Time
+	AcknowledgedAt time.Time
+	AcknowledgedBy string
+	Checks         []HistoricalStat
+}
+
+// Alert is an entry in the alert history: a notification sent for a
+// monitor, or an acknowledgement
+type Alert struct {
+	ID          int
+	MonitorID   int
+	MonitorName string
+	IncidentID  int
+	Event       string
+	Channels    []string
+	Message     string
+	CreatedAt   time.Time
+}
+
+// Delivery states of a queued notification
+const (
+	NotificationPending   = "pending"
+	NotificationDelivered = "delivered"
+	NotificationFailed    = "failed"
+)
+
+// Notification is an event queued for delivery to one channel. Payload is
+// only filled in for delivery, and Log only by ListNotifications.
+type Notification struct {
+	ID            int
+	MonitorID     int
+	MonitorName   string
+	Channel       string
+	Event         string
+	Message       string
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	DeliveredAt   time.Time
+	Payload       []byte
+	Log           []DeliveryAttempt
+}
+
+// DeliveryAttempt is one try at delivering a notification. StatusCode is
+// the HTTP status for channels that use HTTP, and zero otherwise.
+type DeliveryAttempt struct {
+	Attempt    int
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+// StateChange describes how recording a check changed a monitor's status.
+// From is empty when the monitor had no status yet, and Incident is set
+// when the check opened, extended or closed an incident.
+type StateChange struct {
+	From     string
+	To       string
+	Incident *Incident
+}
+
+// Metric is a performance value reported by an exec monitor's command.
+// Warn, Crit, Min and Max are kept as reported since thresholds may be ranges.
+type Metric struct {
+	Label string
+	Value float64
+	Unit  string
+	Warn  string
+	Crit  string
+	Min   string
+	Max   string
+}
+
+// MaintenanceWindow is a period when the monitors it names, or those with
+// any of its tags, are under maintenance. One-off windows run from Start to
+// End. Recurring windows start on Schedule, a cron expression or weekly
+// days and time like "sat,sun 02:00", in Timezone and last for Duration.
+// Active and NextStart are filled in when windows are listed.
+type MaintenanceWindow struct {
+	ID        int
+	Name      string
+	Monitors  []string
+	Tags      []string
+	Mode      string
+	Start     time.Time
+	End       time.Time
+	Schedule  string
+	Duration  time.Duration
+	Timezone  string
+	Active    bool
+	NextStart time.Time
+	CreatedAt time.Time
+}
+
+// SchedulerStats describes the load on the daemon's check scheduler
+type SchedulerStats struct {
+	Workers    int
+	Monitors   int
+	QueueDepth int
+	Running    int
+	Completed  uint64
+	Skipped    uint64
+	LastLag    time.Duration
+	AvgLag     time.Duration
+	MaxLag     time.Duration
+}
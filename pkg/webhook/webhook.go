@@ -0,0 +1,114 @@
+// Package webhook signs and verifies the webhook notifications sent by go-up.
+//
+// When a webhook channel has a secret, each request carries three headers:
+//
+//	X-Go-Up-Event-Id:  the event's ID, the same for every delivery attempt
+//	X-Go-Up-Timestamp: when the request was signed, in Unix seconds
+//	X-Go-Up-Signature: v1=<hex HMAC-SHA256 of "timestamp.id.body">
+//
+// Receivers should check the signature with Verify or VerifyRequest, which
+// reject requests signed too long ago so they can't be replayed, and use the
+// event ID to ignore deliveries they have already handled:
+//
+//	http.HandleFunc("/hooks/go-up", func(w http.ResponseWriter, r *http.Request) {
+//		body, err := webhook.VerifyRequest(r, secret, webhook.DefaultTolerance)
+//		if err != nil {
+//			http.Error(w, err.Error(), http.StatusUnauthorized)
+//			return
+//		}
+//		// handle body
+//	})
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers set on signed requests
+const (
+	EventIDHeader   = "X-Go-Up-Event-Id"
+	TimestampHeader = "X-Go-Up-Timestamp"
+	SignatureHeader = "X-Go-Up-Signature"
+)
+
+// DefaultTolerance is how old a signature can be before it is rejected
+const DefaultTolerance = 5 * time.Minute
+
+// signatureVersion prefixes signatures so the scheme can change later
+const signatureVersion = "v1="
+
+var (
+	ErrMissingSignature = errors.New("webhook: missing signature headers")
+	ErrInvalidTimestamp = errors.New("webhook: invalid timestamp")
+	ErrExpired          = errors.New("webhook: signature timestamp outside tolerance")
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+)
+
+// Sign returns the signature of a request body for an event ID and time
+func Sign(secret, id string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write([]byte(id))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetHeaders signs a request body and sets the signature headers
+func SetHeaders(header http.Header, secret, id string, timestamp time.Time, body []byte) {
+	header.Set(EventIDHeader, id)
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	header.Set(SignatureHeader, Sign(secret, id, timestamp, body))
+}
+
+// Verify checks the signature headers match the body and were signed
+// within tolerance of now. A tolerance of zero skips the time check.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	ts := header.Get(TimestampHeader)
+	signature := header.Get(SignatureHeader)
+	if ts == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	timestamp := time.Unix(unix, 0)
+	if tolerance > 0 {
+		if age := time.Since(timestamp); age > tolerance || age < -tolerance {
+			return ErrExpired
+		}
+	}
+
+	expected := Sign(secret, header.Get(EventIDHeader), timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyRequest reads and verifies a request's body, returning it. The body
+// is also replaced so handlers can read it again.
+func VerifyRequest(r *http.Request, secret string, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := Verify(secret, r.Header, body, tolerance); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	secret = "s3cret"
+	id     = "0123456789abcdef0123456789abcdef"
+)
+
+var body = []byte(`{"event":"down","monitor":"api"}`)
+
+func signedHeader(timestamp time.Time) http.Header {
+	header := make(http.Header)
+	SetHeaders(header, secret, id, timestamp, body)
+	return header
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	if err := Verify(secret, signedHeader(time.Now()), body, DefaultTolerance); err != nil {
+		t.Fatalf("Verify() = %v, want nil", err)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(header http.Header, body []byte) []byte
+	}{
+		{"body", func(header http.Header, body []byte) []byte {
+			return bytes.Replace(body, []byte("down"), []byte("up"), 1)
+		}},
+		{"event id", func(header http.Header, body []byte) []byte {
+			header.Set(EventIDHeader, "another-event")
+			return body
+		}},
+		{"timestamp", func(header http.Header, body []byte) []byte {
+			ts, _ := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+			header.Set(TimestampHeader, strconv.FormatInt(ts+1, 10))
+			return body
+		}},
+		{"signature", func(header http.Header, body []byte) []byte {
+			header.Set(SignatureHeader, Sign("wrong secret", id, time.Now(), body))
+			return body
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := signedHeader(time.Now())
+			tampered := tt.tamper(header, bytes.Clone(body))
+			if err := Verify(secret, header, tampered, DefaultTolerance); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify() = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestVerifyTolerance(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   error
+	}{
+		{"recent", -DefaultTolerance + time.Minute, nil},
+		{"slightly ahead", DefaultTolerance - time.Minute, nil},
+		{"too old", -DefaultTolerance - time.Minute, ErrExpired},
+		{"too far ahead", DefaultTolerance + time.Minute, ErrExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := signedHeader(time.Now().Add(tt.offset))
+			if err := Verify(secret, header, body, DefaultTolerance); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("zero tolerance", func(t *testing.T) {
+		header := signedHeader(time.Now().Add(-24 * time.Hour))
+		if err := Verify(secret, header, body, 0); err != nil {
+			t.Errorf("Verify() = %v, want nil", err)
+		}
+	})
+}
+
+func TestVerifyBadHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(header http.Header)
+		want   error
+	}{
+		{"no timestamp", func(header http.Header) { header.Del(TimestampHeader) }, ErrMissingSignature},
+		{"no signature", func(header http.Header) { header.Del(SignatureHeader) }, ErrMissingSignature},
+		{"malformed timestamp", func(header http.Header) { header.Set(TimestampHeader, "yesterday") }, ErrInvalidTimestamp},
+		{"malformed signature", func(header http.Header) { header.Set(SignatureHeader, "v1=not-hex") }, ErrInvalidSignature},
+		{"unknown version", func(header http.Header) {
+			header.Set(SignatureHeader, "v0="+header.Get(SignatureHeader)[len(signatureVersion):])
+		}, ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := signedHeader(time.Now())
+			tt.modify(header)
+			if err := Verify(secret, header, body, DefaultTolerance); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified, err := VerifyRequest(r, secret, DefaultTolerance)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		again, _ := io.ReadAll(r.Body)
+		if !bytes.Equal(verified, body) || !bytes.Equal(again, body) {
+			http.Error(w, "body changed", http.StatusInternalServerError)
+		}
+	})
+
+	signed := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(body))
+	SetHeaders(signed.Header, secret, id, time.Now(), body)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, signed)
+	if rec.Code != http.StatusOK {
+		t.Errorf("signed request: got %d %s", rec.Code, rec.Body)
+	}
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(body))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, unsigned)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned request: got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}